@@ -2,17 +2,19 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
-	"os"
+	"strconv"
 	"tracker-backend/internal/app"
 	"tracker-backend/internal/app/dependencies"
 	"tracker-backend/internal/app/repository"
 	"tracker-backend/internal/config"
+	"tracker-backend/internal/pkg/mailer"
 	"tracker-backend/internal/pkg/storage"
 )
 
 func main() {
-	config.MustLoadConfig()
+	cfg := config.MustLoadConfig()
 
 	// TODO: init logger (slog)
 
@@ -22,8 +24,8 @@ func main() {
 	// create mongodb connection
 	mongoClient, err := storage.NewMongoClient(
 		ctx,
-		os.Getenv(config.MongoURLEnvName),
-		os.Getenv(config.MongoDBNameEnvName),
+		cfg.Mongo.URL,
+		cfg.Mongo.DBName,
 	)
 	if err != nil {
 		log.Fatalf("failed to connect mongodb %s", err.Error())
@@ -36,21 +38,75 @@ func main() {
 	// create redis connection
 	redisClient, err := storage.NewRedisClient(
 		ctx,
-		os.Getenv(config.RedisHostEnvName),
-		os.Getenv(config.RedisPortEnvName),
+		cfg.Redis.Host,
+		strconv.Itoa(cfg.Redis.Port),
 	)
 	if err != nil {
 		log.Fatalf("failed to connect redis %s", err.Error())
 	}
 	defer redisClient.Close()
 
+	// create media blob store
+	blobStore, err := newBlobStore(ctx, cfg)
+	if err != nil {
+		log.Fatalf("failed to init blob store %s", err.Error())
+	}
+
+	// create mailer
+	mailer, err := newMailer(cfg.Mail)
+	if err != nil {
+		log.Fatalf("failed to init mailer %s", err.Error())
+	}
+
 	// init dependencies
-	deps := dependencies.InitDependencies(ctx, repo)
+	deps := dependencies.InitDependencies(ctx, cfg, repo, redisClient, blobStore, mailer)
 
 	// create app instance
 	app := app.NewApp(
-		os.Getenv(config.PortEnvName), deps,
+		strconv.Itoa(cfg.Port), deps, redisClient,
 	)
 
 	app.Run()
 }
+
+// newBlobStore creates configured blob store,
+// local store keeps files in public dir
+func newBlobStore(ctx context.Context, cfg *config.Config) (storage.BlobStore, error) {
+	switch cfg.BlobStore.Type {
+	case "local":
+		return storage.NewLocalBlobStore(cfg.PublicPath), nil
+	case "s3":
+		s3 := cfg.BlobStore.S3
+		return storage.NewS3BlobStore(ctx, storage.S3Options{
+			Endpoint:  s3.Endpoint,
+			AccessKey: s3.AccessKey,
+			SecretKey: s3.SecretKey,
+			Bucket:    s3.Bucket,
+			Region:    s3.Region,
+			UseSSL:    s3.UseSSL,
+		})
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", cfg.BlobStore.Type)
+	}
+}
+
+// newMailer creates configured mailer,
+// emails are logged by default
+func newMailer(cfg config.MailConfig) (mailer.Mailer, error) {
+	switch cfg.Mailer {
+	case "log":
+		return mailer.NewLogMailer(), nil
+	case "file":
+		return mailer.NewFileMailer(cfg.Dir, cfg.From)
+	case "smtp":
+		return mailer.NewSMTPMailer(mailer.SMTPOptions{
+			Host:     cfg.SMTP.Host,
+			Port:     strconv.Itoa(cfg.SMTP.Port),
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.From,
+		})
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", cfg.Mailer)
+	}
+}
@@ -1,11 +1,13 @@
 package album
 
 import (
+	"errors"
 	"net/http"
 	albumType "tracker-backend/internal/album/type"
 	"tracker-backend/internal/auth"
 	genreType "tracker-backend/internal/genre/type"
 	"tracker-backend/internal/pkg/response"
+	"tracker-backend/internal/pkg/service"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -19,7 +21,6 @@ type AlbumHandler struct {
 
 func NewAlbumHandler(s *AlbumService) *AlbumHandler {
 	v := validator.New()
-	v.RegisterValidation("status", albumType.ValidateStatus)
 	v.RegisterValidation("year", albumType.ValidateYear)
 	v.RegisterValidation("genres", genreType.ValidateGenres)
 
@@ -52,7 +53,16 @@ func (h *AlbumHandler) Create(w http.ResponseWriter, r *http.Request) {
 	// execute service function
 	album, err := h.Service.Create(ctx, userID, &req)
 	if err != nil {
-		render.Status(r, http.StatusBadRequest)
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("artist not found"))
+			return
+		case errors.Is(err, service.ErrAccessDenied):
+			render.Status(r, http.StatusForbidden)
+		default:
+			render.Status(r, http.StatusBadRequest)
+		}
 		render.JSON(w, r, response.Error(err.Error()))
 		return
 	}
@@ -85,7 +95,16 @@ func (h *AlbumHandler) Update(w http.ResponseWriter, r *http.Request) {
 	// execute service function
 	album, err := h.Service.Update(ctx, userID, albumID, &req)
 	if err != nil {
-		render.Status(r, http.StatusBadRequest)
+		switch {
+		case errors.Is(err, service.ErrAccessDenied):
+			render.Status(r, http.StatusForbidden)
+		case errors.Is(err, service.ErrNotFound):
+			render.Status(r, http.StatusNotFound)
+		case errors.Is(err, ErrTitleTaken), errors.Is(err, ErrEmptyAlbum):
+			render.Status(r, http.StatusConflict)
+		default:
+			render.Status(r, http.StatusBadRequest)
+		}
 		render.JSON(w, r, response.Error(err.Error()))
 		return
 	}
@@ -97,7 +116,6 @@ func (h *AlbumHandler) Update(w http.ResponseWriter, r *http.Request) {
 func (h *AlbumHandler) UpdateCover(w http.ResponseWriter, r *http.Request) {
 	// get context keys
 	ctx := r.Context()
-	userID := ctx.Value(auth.UserIDKey).(string)
 	albumID := chi.URLParam(r, "id")
 
 	// decode form data file
@@ -110,7 +128,7 @@ func (h *AlbumHandler) UpdateCover(w http.ResponseWriter, r *http.Request) {
 	defer file.Close()
 
 	// execute service function
-	album, err := h.Service.UpdateCover(ctx, userID, albumID, &file, fileHeader)
+	album, err := h.Service.UpdateCover(ctx, albumID, &file, fileHeader)
 	if err != nil {
 		render.Status(r, http.StatusBadRequest)
 		render.JSON(w, r, response.Error(err.Error()))
@@ -140,17 +158,20 @@ func (h *AlbumHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 func (h *AlbumHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	// get context keys
 	ctx := r.Context()
-	userID := ctx.Value(auth.UserIDKey).(string)
 	albumID := chi.URLParam(r, "id")
 
 	// execute service function
-	err := h.Service.Delete(ctx, userID, albumID)
+	deletion, err := h.Service.Delete(ctx, albumID)
 	if err != nil {
-		render.Status(r, http.StatusBadRequest)
+		if errors.Is(err, service.ErrNotFound) {
+			render.Status(r, http.StatusNotFound)
+		} else {
+			render.Status(r, http.StatusInternalServerError)
+		}
 		render.JSON(w, r, response.Error(err.Error()))
 		return
 	}
 
-	// send response
-	render.Status(r, http.StatusNoContent)
+	// send deletion report
+	render.JSON(w, r, deletion.ToResponse())
 }
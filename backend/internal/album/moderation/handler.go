@@ -1 +1,123 @@
 package albumModeration
+
+import (
+	"errors"
+	"net/http"
+	albumType "tracker-backend/internal/album/type"
+	"tracker-backend/internal/auth"
+	"tracker-backend/internal/auth/ownership"
+	"tracker-backend/internal/pkg/pagination"
+	"tracker-backend/internal/pkg/response"
+	"tracker-backend/internal/pkg/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type AlbumModerationHandler struct {
+	Service   *AlbumModerationService
+	Validator *validator.Validate
+}
+
+func NewAlbumModerationHandler(s *AlbumModerationService) *AlbumModerationHandler {
+	v := validator.New()
+	v.RegisterValidation("status", albumType.ValidateStatus)
+
+	return &AlbumModerationHandler{
+		Service:   s,
+		Validator: v,
+	}
+}
+
+// PUT /album/{id}/moderation
+func (h *AlbumModerationHandler) Moderate(w http.ResponseWriter, r *http.Request) {
+	// get context keys
+	ctx := r.Context()
+	userID := ctx.Value(auth.UserIDKey).(string)
+	albumID := chi.URLParam(r, "id")
+
+	// decode json
+	var req albumType.ModerationRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid request body"))
+		return
+	}
+
+	// validate request
+	if err := h.Validator.Struct(req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.ValidationErrorsResp(err.(validator.ValidationErrors)))
+		return
+	}
+
+	// execute service function
+	album, err := h.Service.Moderate(ctx, albumID, userID, &req)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			render.Status(r, http.StatusNotFound)
+		} else if errors.Is(err, ErrNotOnModeration) {
+			render.Status(r, http.StatusConflict)
+		} else {
+			render.Status(r, http.StatusInternalServerError)
+		}
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
+
+	// send response
+	render.JSON(w, r, album.ToResponse())
+}
+
+// GET /album/on-moderation
+func (h *AlbumModerationHandler) GetOnModeration(w http.ResponseWriter, r *http.Request) {
+	// get context keys
+	ctx := r.Context()
+
+	// execute service function
+	params := pagination.FromRequest(r)
+	albums, total, err := h.Service.GetOnModeration(ctx, params)
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
+
+	// execution result to response
+	res := albumType.AlbumListResponse{
+		Items: make([]albumType.AlbumResponse, len(albums)),
+		Total: total,
+		Page:  params.Page,
+		Limit: params.Limit,
+	}
+	for i, a := range albums {
+		res.Items[i] = a.ToResponse()
+	}
+
+	// send response
+	render.JSON(w, r, res)
+}
+
+// GET /album/{id}/moderation
+func (h *AlbumModerationHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	// get album loaded by ownership middleware
+	ctx := r.Context()
+	var album albumType.Album
+	if err := ownership.ResourceFromContext(ctx, &album); err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error("failed to get album"))
+		return
+	}
+
+	// execute service function
+	history, err := h.Service.GetHistory(ctx, &album)
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
+
+	// send response
+	render.JSON(w, r, history)
+}
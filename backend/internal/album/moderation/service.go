@@ -1 +1,176 @@
 package albumModeration
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+	albumType "tracker-backend/internal/album/type"
+	"tracker-backend/internal/pkg/pagination"
+	"tracker-backend/internal/pkg/service"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	ErrNotOnModeration = errors.New("album is not on moderation")
+)
+
+type AlbumModerationService struct {
+	albumsCol      *mongo.Collection
+	moderationsCol *mongo.Collection
+}
+
+func NewAlbumModerationService(
+	albumsCol, moderationsCol *mongo.Collection,
+) *AlbumModerationService {
+	return &AlbumModerationService{
+		albumsCol:      albumsCol,
+		moderationsCol: moderationsCol,
+	}
+}
+
+// RecordSubmission saves the fact that album was sent to moderation by its owner
+func (s *AlbumModerationService) RecordSubmission(
+	ctx context.Context, albumID, userID string,
+) error {
+	return s.record(ctx, albumID, userID, albumType.StatusOnModeration, "")
+}
+
+// Moderate approves or denies album which is on moderation
+func (s *AlbumModerationService) Moderate(
+	ctx context.Context, albumID, moderatorID string, req *albumType.ModerationRequest,
+) (*albumType.Album, error) {
+	// configure logger
+	logger := slog.With(slog.String("function", "albumModeration.AlbumModerationService.Moderate"))
+
+	// reason is kept for denied albums only
+	reason := ""
+	if req.Status == albumType.StatusDenied {
+		reason = req.Reason
+	}
+
+	// only album which is on moderation can be moderated
+	var album albumType.Album
+	err := s.albumsCol.FindOneAndUpdate(ctx,
+		bson.M{"id": albumID, "status": albumType.StatusOnModeration},
+		bson.M{"$set": bson.M{
+			"status":           req.Status,
+			"moderationReason": reason,
+			"updatedAt":        time.Now(),
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&album)
+
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			logger.Warn("failed to update album", slog.String("error", err.Error()))
+			return nil, errors.New("failed to moderate album")
+		}
+		// check album existence
+		count, err := s.albumsCol.CountDocuments(ctx, bson.M{"id": albumID})
+		if err != nil {
+			return nil, errors.New("failed to check album existence")
+		}
+		if count < 1 {
+			return nil, service.ErrNotFound
+		}
+		return nil, ErrNotOnModeration
+	}
+
+	// save history entry
+	if err := s.record(ctx, albumID, moderatorID, req.Status, reason); err != nil {
+		logger.Warn("failed to save moderation record", slog.String("error", err.Error()))
+	}
+
+	logger.Info("album moderated",
+		slog.Group("info",
+			slog.String("albumID", albumID),
+			slog.String("moderatorID", moderatorID),
+			slog.String("status", req.Status),
+		),
+	)
+
+	return &album, nil
+}
+
+// GetOnModeration returns albums waiting for moderation
+// the oldest submissions come first
+func (s *AlbumModerationService) GetOnModeration(
+	ctx context.Context, params pagination.Params,
+) ([]albumType.Album, int64, error) {
+	filter := bson.M{"status": albumType.StatusOnModeration}
+
+	total, err := s.albumsCol.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, errors.New("failed to count albums")
+	}
+
+	cur, err := s.albumsCol.Find(ctx, filter,
+		options.Find().
+			SetSort(bson.D{{Key: "updatedAt", Value: 1}}).
+			SetSkip(params.Skip()).
+			SetLimit(params.Limit),
+	)
+	if err != nil {
+		return nil, 0, errors.New("failed to find albums")
+	}
+	defer cur.Close(ctx)
+
+	albums := []albumType.Album{}
+	if err := cur.All(ctx, &albums); err != nil {
+		return nil, 0, errors.New("failed to decode cursor")
+	}
+
+	return albums, total, nil
+}
+
+// GetHistory returns album moderation history
+// access is checked by ownership middleware
+func (s *AlbumModerationService) GetHistory(
+	ctx context.Context, album *albumType.Album,
+) (*albumType.ModerationHistoryResponse, error) {
+	// find history records, newest first
+	cur, err := s.moderationsCol.Find(ctx,
+		bson.M{"albumID": album.ID},
+		options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}),
+	)
+	if err != nil {
+		return nil, errors.New("failed to find moderation history")
+	}
+	defer cur.Close(ctx)
+
+	var records []albumType.ModerationRecord
+	if err := cur.All(ctx, &records); err != nil {
+		return nil, errors.New("failed to decode cursor")
+	}
+
+	res := &albumType.ModerationHistoryResponse{
+		AlbumID: album.ID,
+		Status:  album.Status,
+		Reason:  album.ModerationReason,
+		History: make([]albumType.ModerationRecordResponse, len(records)),
+	}
+	for i, r := range records {
+		res.History[i] = r.ToResponse()
+	}
+
+	return res, nil
+}
+
+func (s *AlbumModerationService) record(
+	ctx context.Context, albumID, userID, status, reason string,
+) error {
+	_, err := s.moderationsCol.InsertOne(ctx, &albumType.ModerationRecord{
+		ID:        uuid.NewString(),
+		AlbumID:   albumID,
+		UserID:    userID,
+		Status:    status,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	})
+	return err
+}
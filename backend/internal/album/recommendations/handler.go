@@ -1 +1,51 @@
 package albumRecommendations
+
+import (
+	"net/http"
+	albumType "tracker-backend/internal/album/type"
+	"tracker-backend/internal/auth"
+	"tracker-backend/internal/pkg/pagination"
+	"tracker-backend/internal/pkg/response"
+
+	"github.com/go-chi/render"
+)
+
+type AlbumRecommendationsHandler struct {
+	Service *AlbumRecommendationsService
+}
+
+func NewAlbumRecommendationsHandler(s *AlbumRecommendationsService) *AlbumRecommendationsHandler {
+	return &AlbumRecommendationsHandler{
+		Service: s,
+	}
+}
+
+// GET /album/recommendations
+func (h *AlbumRecommendationsHandler) GetRecommendations(w http.ResponseWriter, r *http.Request) {
+	// get context keys
+	ctx := r.Context()
+	userID := ctx.Value(auth.UserIDKey).(string)
+
+	// execute service function
+	params := pagination.FromRequest(r)
+	recommendations, total, err := h.Service.GetRecommendations(ctx, userID, params)
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
+
+	// execution result to response
+	res := albumType.RecommendationListResponse{
+		Items: make([]albumType.RecommendationResponse, len(recommendations)),
+		Total: total,
+		Page:  params.Page,
+		Limit: params.Limit,
+	}
+	for i, rec := range recommendations {
+		res.Items[i] = rec.ToResponse()
+	}
+
+	// send response
+	render.JSON(w, r, res)
+}
@@ -1 +1,304 @@
 package albumRecommendations
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	albumType "tracker-backend/internal/album/type"
+	artistType "tracker-backend/internal/artist/type"
+	genreType "tracker-backend/internal/genre/type"
+	"tracker-backend/internal/pkg/pagination"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// number of genres mentioned in explanation
+const explainedGenresCount = 3
+
+type AlbumRecommendationsService struct {
+	albumsCol          *mongo.Collection
+	artistsCol         *mongo.Collection
+	playlistsCol       *mongo.Collection
+	genreModelProvider GenreModelProvider
+}
+
+type GenreModelProvider interface {
+	GetModel(ctx context.Context, userID string) (genreType.GenreModel, error)
+}
+
+func NewAlbumRecommendationsService(
+	albumsCol, artistsCol, playlistsCol *mongo.Collection,
+	genreModelProvider GenreModelProvider,
+) *AlbumRecommendationsService {
+	return &AlbumRecommendationsService{
+		albumsCol:          albumsCol,
+		artistsCol:         artistsCol,
+		playlistsCol:       playlistsCol,
+		genreModelProvider: genreModelProvider,
+	}
+}
+
+// taste is a user genre preferences vector
+type taste struct {
+	// genre weights, sum of weights is 1
+	genres genreType.GenreModel
+	// albums user already knows
+	knownAlbumIDs []string
+}
+
+// weightedGenre is a taste vector element passed to aggregation
+type weightedGenre struct {
+	Genre  string  `bson:"k"`
+	Weight float64 `bson:"v"`
+}
+
+type recommendationsResult struct {
+	Items []albumType.Recommendation `bson:"items"`
+	Total []struct {
+		Count int64 `bson:"count"`
+	} `bson:"total"`
+}
+
+// GetRecommendations returns albums picked by user genre preferences.
+// Moderated and not hidden albums are scored by cosine similarity
+// of album genres and user taste vector.
+// Users without preferences get the newest albums
+func (s *AlbumRecommendationsService) GetRecommendations(
+	ctx context.Context, userID string, params pagination.Params,
+) ([]albumType.Recommendation, int64, error) {
+	// configure logger
+	logger := slog.With(slog.String("function", "albumRecommendations.AlbumRecommendationsService.GetRecommendations"))
+
+	t, err := s.getTaste(ctx, userID)
+	if err != nil {
+		logger.Warn("failed to get user taste", slog.String("error", err.Error()))
+		return nil, 0, errors.New("failed to get user preferences")
+	}
+
+	ownArtistIDs, err := artistType.OwnArtistIDs(ctx, s.artistsCol, userID)
+	if err != nil {
+		logger.Warn("failed to get user artists", slog.String("error", err.Error()))
+		return nil, 0, errors.New("failed to get user artists")
+	}
+
+	// public albums user doesn't know yet
+	match := albumType.VisibilityFilter("", nil)
+	match["id"] = bson.M{"$nin": t.knownAlbumIDs}
+	match["artistID"] = bson.M{"$nin": ownArtistIDs}
+
+	var pipeline []bson.M
+	if len(t.genres) == 0 {
+		pipeline = newReleasesPipeline(match, params)
+	} else {
+		pipeline = tastePipeline(match, t, params)
+	}
+
+	cur, err := s.albumsCol.Aggregate(ctx, pipeline)
+	if err != nil {
+		logger.Warn("failed to aggregate", slog.String("error", err.Error()))
+		return nil, 0, errors.New("failed to get recommendations")
+	}
+	defer cur.Close(ctx)
+
+	var result recommendationsResult
+	if cur.Next(ctx) {
+		if err := cur.Decode(&result); err != nil {
+			logger.Warn("failed to decode", slog.String("error", err.Error()))
+			return nil, 0, errors.New("failed to decode recommendations")
+		}
+	}
+
+	var total int64
+	if len(result.Total) > 0 {
+		total = result.Total[0].Count
+	}
+
+	// explain each recommendation
+	for i := range result.Items {
+		explain(&result.Items[i])
+	}
+
+	return result.Items, total, nil
+}
+
+// getTaste builds user genre preferences from tracks
+// of the default "My Choice" playlist and listening genre model
+func (s *AlbumRecommendationsService) getTaste(
+	ctx context.Context, userID string,
+) (*taste, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"userID": userID, "isDefault": true}},
+		{"$unwind": "$trackIDs"},
+		{"$lookup": bson.M{
+			"from":         "tracks",
+			"localField":   "trackIDs",
+			"foreignField": "id",
+			"as":           "track",
+		}},
+		{"$unwind": "$track"},
+		{"$unwind": "$track.genre"},
+		{"$group": bson.M{
+			"_id":    bson.M{"$toLower": bson.M{"$trim": bson.M{"input": "$track.genre"}}},
+			"count":  bson.M{"$sum": 1},
+			"albums": bson.M{"$addToSet": "$track.album"},
+		}},
+	}
+
+	cur, err := s.playlistsCol.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var groups []struct {
+		Genre  string   `bson:"_id"`
+		Count  float64  `bson:"count"`
+		Albums []string `bson:"albums"`
+	}
+	if err := cur.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	t := &taste{knownAlbumIDs: []string{}}
+	playlistModel := genreType.GenreModel{}
+	for _, g := range groups {
+		for _, albumID := range g.Albums {
+			if !slices.Contains(t.knownAlbumIDs, albumID) {
+				t.knownAlbumIDs = append(t.knownAlbumIDs, albumID)
+			}
+		}
+		if slices.Contains(genreType.AllowedGenres, g.Genre) {
+			playlistModel[g.Genre] = g.Count
+		}
+	}
+
+	// get model built from listens and playlist additions
+	listenModel, err := s.genreModelProvider.GetModel(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// both sources have equal influence
+	combined := playlistModel.Normalized()
+	for g, w := range listenModel.Normalized() {
+		combined[g] += w
+	}
+	t.genres = combined.Normalized()
+
+	return t, nil
+}
+
+// tastePipeline scores albums by cosine similarity
+// of album genres and taste vector
+func tastePipeline(match bson.M, t *taste, params pagination.Params) []bson.M {
+	// taste vector sorted by weight, so matched genres are sorted too
+	vector := make([]weightedGenre, 0, len(t.genres))
+	var norm float64
+	for g, w := range t.genres {
+		vector = append(vector, weightedGenre{Genre: g, Weight: w})
+		norm += w * w
+	}
+	sort.Slice(vector, func(i, j int) bool {
+		if vector[i].Weight == vector[j].Weight {
+			return vector[i].Genre < vector[j].Genre
+		}
+		return vector[i].Weight > vector[j].Weight
+	})
+	norm = math.Sqrt(norm)
+
+	genres := make([]string, len(vector))
+	for i, v := range vector {
+		genres[i] = v.Genre
+	}
+
+	return []bson.M{
+		{"$match": match},
+		{"$addFields": bson.M{
+			"_genres": bson.M{"$map": bson.M{
+				"input": bson.M{"$ifNull": bson.A{"$genres", bson.A{}}},
+				"in":    bson.M{"$toLower": bson.M{"$trim": bson.M{"input": "$$this"}}},
+			}},
+		}},
+		{"$match": bson.M{"_genres": bson.M{"$in": genres}}},
+		{"$addFields": bson.M{
+			"_matched": bson.M{"$filter": bson.M{
+				"input": vector,
+				"as":    "g",
+				"cond":  bson.M{"$in": bson.A{"$$g.k", "$_genres"}},
+			}},
+		}},
+		{"$addFields": bson.M{
+			"score": bson.M{"$round": bson.A{
+				bson.M{"$divide": bson.A{
+					bson.M{"$sum": "$_matched.v"},
+					bson.M{"$multiply": bson.A{norm, bson.M{"$sqrt": bson.M{"$size": "$_genres"}}}},
+				}},
+				4,
+			}},
+		}},
+		{"$sort": bson.D{
+			{Key: "score", Value: -1},
+			{Key: "createdAt", Value: -1},
+			{Key: "id", Value: 1},
+		}},
+		paginate(params, bson.M{
+			"_id":           0,
+			"album":         "$$ROOT",
+			"score":         1,
+			"matchedGenres": "$_matched.k",
+		}),
+	}
+}
+
+// newReleasesPipeline returns the newest albums
+func newReleasesPipeline(match bson.M, params pagination.Params) []bson.M {
+	return []bson.M{
+		{"$match": match},
+		{"$sort": bson.D{
+			{Key: "createdAt", Value: -1},
+			{Key: "id", Value: 1},
+		}},
+		paginate(params, bson.M{
+			"_id":   0,
+			"album": "$$ROOT",
+			"score": bson.M{"$literal": 0},
+		}),
+	}
+}
+
+// paginate splits sorted documents to requested page and total count
+func paginate(params pagination.Params, project bson.M) bson.M {
+	return bson.M{
+		"$facet": bson.M{
+			"items": bson.A{
+				bson.M{"$skip": params.Skip()},
+				bson.M{"$limit": params.Limit},
+				bson.M{"$project": project},
+			},
+			"total": bson.A{
+				bson.M{"$count": "count"},
+			},
+		},
+	}
+}
+
+// explain sets human readable reason of recommendation
+func explain(r *albumType.Recommendation) {
+	if len(r.MatchedGenres) == 0 {
+		r.Reason = albumType.ReasonNewRelease
+		r.Explanation = "New release"
+		return
+	}
+
+	genres := r.MatchedGenres
+	if len(genres) > explainedGenresCount {
+		genres = genres[:explainedGenresCount]
+	}
+	r.Reason = albumType.ReasonTaste
+	r.Explanation = "Because you like " + strings.Join(genres, ", ")
+}
@@ -1,8 +1,12 @@
 package album
 
 import (
+	albumModeration "tracker-backend/internal/album/moderation"
+	albumRecommendations "tracker-backend/internal/album/recommendations"
 	albumTracks "tracker-backend/internal/album/tracks"
 	"tracker-backend/internal/auth"
+	"tracker-backend/internal/auth/middleware"
+	"tracker-backend/internal/auth/ownership"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -11,21 +15,45 @@ func RegisterAlbumRoutes(
 	router chi.Router,
 	albumSvc *AlbumService,
 	albumTracksSvc *albumTracks.AlbumTracksService,
+	albumModerationSvc *albumModeration.AlbumModerationService,
+	albumRecommendationsSvc *albumRecommendations.AlbumRecommendationsService,
+	ownershipSvc *ownership.OwnershipService,
 	authMiddleware auth.MiddlewareFunc,
+	uploadMiddleware auth.MiddlewareFunc,
 ) {
 	h := NewAlbumHandler(albumSvc)
 	ht := albumTracks.NewAlbumTracksHandler(albumTracksSvc)
+	hm := albumModeration.NewAlbumModerationHandler(albumModerationSvc)
+	hr := albumRecommendations.NewAlbumRecommendationsHandler(albumRecommendationsSvc)
 
 	router.Route("/album", func(r chi.Router) {
 		r.Use(authMiddleware)
+		r.Get("/recommendations", hr.GetRecommendations)
 		r.Get("/{id}/tracks", ht.GetAlbumTracks)
 
+		// owner or moderator
+		r.With(ownershipSvc.Require(ownership.ResourceAlbum, auth.RoleModerator)).
+			Get("/{id}/moderation", hm.GetHistory)
+
+		// content moderation
 		r.Group(func(mr chi.Router) {
-			mr.Use(authMiddleware)
-			mr.Post("/", h.Create)
-			mr.Put("/{id}", h.Update)
-			mr.Delete("/{id}", h.Delete)
+			mr.Use(middleware.RequireRole(auth.RoleModerator))
+			mr.Get("/on-moderation", hm.GetOnModeration)
+			mr.Put("/{id}/moderation", hm.Moderate)
+		})
+
+		// owner only
+		r.Group(func(or chi.Router) {
+			or.Use(ownershipSvc.Require(ownership.ResourceAlbum, ownership.NoBypass))
+			or.Put("/{id}", h.Update)
+			or.With(uploadMiddleware).Put("/{id}/cover", h.UpdateCover)
 		})
+
+		// owner or admin
+		r.With(ownershipSvc.Require(ownership.ResourceAlbum, auth.RoleAdmin)).
+			Delete("/{id}", h.Delete)
+
+		r.Post("/", h.Create)
 		r.Get("/{id}", h.GetByID)
 	})
 }
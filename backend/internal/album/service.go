@@ -3,16 +3,19 @@ package album
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"mime/multipart"
-	"os"
-	"path/filepath"
+	"path"
 	"strings"
 	"time"
 	albumType "tracker-backend/internal/album/type"
+	"tracker-backend/internal/auth"
 	"tracker-backend/internal/auth/ownership"
+	"tracker-backend/internal/cascade"
 	"tracker-backend/internal/config"
 	uploadfile "tracker-backend/internal/pkg/file"
 	"tracker-backend/internal/pkg/service"
+	"tracker-backend/internal/pkg/storage"
 
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -21,29 +24,49 @@ import (
 )
 
 type AlbumService struct {
-	Col              *mongo.Collection
-	trackChecker     TrackChecker
-	ownershipService *ownership.OwnershipService
+	Col                *mongo.Collection
+	trackChecker       TrackChecker
+	moderationRecorder ModerationRecorder
+	ownershipService   *ownership.OwnershipService
+	albumDeleter       AlbumDeleter
+	blobStore          storage.BlobStore
+}
+
+// AlbumDeleter removes album with its tracks and files
+type AlbumDeleter interface {
+	DeleteAlbum(ctx context.Context, albumID string) (*cascade.Deletion, error)
 }
 
 type TrackChecker interface {
 	IsAnyTracksInAlbum(ctx context.Context, albumID string) (bool, error)
 }
 
+type ModerationRecorder interface {
+	RecordSubmission(ctx context.Context, albumID, userID string) error
+}
+
 var (
-	ErrTitleTaken = errors.New("album with this title already exists")
+	ErrTitleTaken      = errors.New("album with this title already exists")
+	ErrEmptyAlbum      = errors.New("album without tracks cannot be published")
+	ErrNothingToUpdate = errors.New("nothing to update")
 )
 
 func NewAlbumService(
 	albumsCol *mongo.Collection,
 	trackChecker TrackChecker,
+	moderationRecorder ModerationRecorder,
 	ownershipService *ownership.OwnershipService,
+	albumDeleter AlbumDeleter,
+	blobStore storage.BlobStore,
 ) *AlbumService {
 
 	return &AlbumService{
-		Col:              albumsCol,
-		trackChecker:     trackChecker,
-		ownershipService: ownershipService,
+		Col:                albumsCol,
+		trackChecker:       trackChecker,
+		moderationRecorder: moderationRecorder,
+		ownershipService:   ownershipService,
+		albumDeleter:       albumDeleter,
+		blobStore:          blobStore,
 	}
 }
 
@@ -56,19 +79,20 @@ func (s *AlbumService) Create(
 	// validate artist ownership first (you need to implement this check)
 	isOwn, err := s.ownershipService.IsArtistOwner(ctx, userID, req.ArtistID)
 	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return nil, err
+		}
 		return nil, errors.New("failed to check ownership")
 	}
 	if !isOwn {
 		return nil, service.ErrAccessDenied
 	}
 
-	coverPath := filepath.Join(
-		os.Getenv(config.PublicDirPathEnvName),
-		config.CoversDir,
-		"cover_default.jpg",
-	)
+	coverPath := uploadfile.PublicPath(path.Join(config.CoversDir, "cover_default.jpg"))
 
-	// album is hidden by default
+	// album is hidden by default and
+	// can be published by moderator only
+	now := time.Now()
 	album := &albumType.Album{
 		ID:        uuid.NewString(),
 		Title:     req.Title,
@@ -76,9 +100,10 @@ func (s *AlbumService) Create(
 		Year:      req.Year,
 		Genres:    req.Genres,
 		CoverPath: coverPath,
-		Status:    albumType.StatusModerated,
+		Status:    albumType.StatusOnModeration,
 		IsHidden:  true,
-		CreatedAt: time.Now(),
+		CreatedAt: now,
+		UpdatedAt: now,
 	}
 
 	_, err = s.Col.InsertOne(ctx, album)
@@ -89,71 +114,76 @@ func (s *AlbumService) Create(
 		return nil, err
 	}
 
+	// save moderation history entry
+	if err := s.moderationRecorder.RecordSubmission(ctx, album.ID, userID); err != nil {
+		slog.Warn("failed to record album submission",
+			slog.String("function", "album.AlbumService.Create"),
+			slog.String("error", err.Error()),
+		)
+	}
+
 	return album, nil
 }
 
+// UpdateCover uploads new album cover
+// ownership is checked by ownership middleware
 func (s *AlbumService) UpdateCover(
 	ctx context.Context,
-	userID string,
 	albumID string,
 	file *multipart.File,
 	fileHeader *multipart.FileHeader,
 ) (*albumType.Album, error) {
-
-	isOwner, err := s.ownershipService.IsAlbumOwner(ctx, userID, albumID)
-	if err != nil {
-		return nil, errors.New("failed check album ownership")
-	}
-	if !isOwner {
-		return nil, service.ErrAccessDenied
-	}
-
 	filter := bson.M{"id": albumID}
 
 	if err := uploadfile.ValidateFile(fileHeader, uploadfile.AllowedImageExtensions); err != nil {
 		return nil, err
 	}
 
-	coverPath, err := uploadfile.UploadFile(
+	coverKey, err := uploadfile.UploadFile(
+		ctx,
+		s.blobStore,
 		fileHeader,
 		file,
-		"uploads/covers",
+		config.CoversDir,
 		uploadfile.AllowedImageExtensions,
 	)
 	if err != nil {
 		return nil, err
 	}
+	coverPath := uploadfile.PublicPath(coverKey)
 
+	// get previous cover path to remove it after update
 	update := bson.M{"$set": bson.M{"coverPath": coverPath}}
-	var updatedAlbum albumType.Album
+	var album albumType.Album
 	err = s.Col.FindOneAndUpdate(
 		ctx,
 		filter,
 		update,
-		options.FindOneAndUpdate().SetReturnDocument(options.After),
-	).Decode(&updatedAlbum)
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&album)
 
 	if err != nil {
+		s.blobStore.Delete(ctx, coverKey)
 		return nil, errors.New("album not found or not owned by user")
 	}
 
-	return &updatedAlbum, nil
+	// default cover is shared by albums
+	if key, ok := uploadfile.UploadedKey(album.CoverPath, config.CoversDir); ok {
+		s.blobStore.Delete(ctx, key)
+	}
+
+	album.CoverPath = coverPath
+	return &album, nil
 }
 
+// Update updates album metadata and sends album to moderation
+// ownership is checked by ownership middleware
 func (s *AlbumService) Update(
 	ctx context.Context,
 	userID string,
 	albumID string,
 	req *albumType.AlbumUpdateRequest,
 ) (*albumType.Album, error) {
-	isOwner, err := s.ownershipService.IsAlbumOwner(ctx, userID, albumID)
-	if err != nil {
-		return nil, errors.New("failed check album ownership")
-	}
-	if !isOwner {
-		return nil, service.ErrAccessDenied
-	}
-
 	filter := bson.M{"id": albumID}
 	updates := bson.M{}
 
@@ -166,24 +196,33 @@ func (s *AlbumService) Update(
 	if req.Title != nil {
 		updates["title"] = *req.Title
 	}
-	if req.Status != nil {
-		updates["status"] = *req.Status
+	if req.IsHidden != nil {
+		// if album has no tracks - it cannot be published
+		if !*req.IsHidden {
+			hasTracks, err := s.trackChecker.IsAnyTracksInAlbum(ctx, albumID)
+			if err != nil {
+				return nil, err
+			}
+			if !hasTracks {
+				return nil, ErrEmptyAlbum
+			}
+		}
+		updates["isHidden"] = *req.IsHidden
 	}
 
 	if len(updates) <= 0 {
-		return nil, err
+		return nil, ErrNothingToUpdate
 	}
 
-	// TODO: check the status being set
-	// if album has no tracks - it cannot be published
-	// if album is on moderation - the status cannot be changed
-	// moderation is required for any changes in album
-
 	// album change pipeline:
 	// change/creation -> moderation -> publication
+	// moderation is required for any changes in album
+	updates["status"] = albumType.StatusOnModeration
+	updates["moderationReason"] = ""
+	updates["updatedAt"] = time.Now()
 
 	var album albumType.Album
-	err = s.Col.FindOneAndUpdate(ctx,
+	err := s.Col.FindOneAndUpdate(ctx,
 		filter, bson.M{"$set": updates},
 		options.FindOneAndUpdate().SetReturnDocument(options.After),
 	).Decode(&album)
@@ -205,6 +244,14 @@ func (s *AlbumService) Update(
 		return nil, errors.New("failed to update")
 	}
 
+	// save moderation history entry
+	if err := s.moderationRecorder.RecordSubmission(ctx, albumID, userID); err != nil {
+		slog.Warn("failed to record album submission",
+			slog.String("function", "album.AlbumService.Update"),
+			slog.String("error", err.Error()),
+		)
+	}
+
 	return &album, nil
 }
 
@@ -232,22 +279,62 @@ func (s *AlbumService) CheckExistence(ctx context.Context, albumID string) (bool
 	return true, nil
 }
 
-func (s *AlbumService) Delete(
-	ctx context.Context, userID string, albumID string,
-) error {
-	isOwn, err := s.ownershipService.IsAlbumOwner(ctx, userID, albumID)
+// IsVisible checks that album is visible to user:
+// moderated and not hidden albums are visible to everyone,
+// albums on moderation to moderators and owner
+func (s *AlbumService) IsVisible(
+	ctx context.Context, albumID, userID string, userRole int,
+) (bool, error) {
+	album, err := s.GetByID(ctx, albumID)
 	if err != nil {
-		return errors.New("failed check album owner")
+		if errors.Is(err, service.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
 	}
-	if !isOwn {
-		return service.ErrAccessDenied
+	return s.isVisible(ctx, album, userID, userRole)
+}
+
+// IsCoverVisible checks that album with cover path is visible to user.
+// Default cover is shared and visible to everyone,
+// uploaded cover not used by any album is hidden
+func (s *AlbumService) IsCoverVisible(
+	ctx context.Context, coverPath, userID string, userRole int,
+) (bool, error) {
+	if _, ok := uploadfile.UploadedKey(coverPath, config.CoversDir); !ok {
+		return true, nil
 	}
-	res, err := s.Col.DeleteOne(ctx, bson.M{"id": albumID})
+
+	var album albumType.Album
+	err := s.Col.FindOne(ctx, bson.M{"coverPath": coverPath}).Decode(&album)
 	if err != nil {
-		return errors.New("failed to delete")
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return false, nil
+		}
+		return false, err
+	}
+	return s.isVisible(ctx, &album, userID, userRole)
+}
+
+func (s *AlbumService) isVisible(
+	ctx context.Context, album *albumType.Album, userID string, userRole int,
+) (bool, error) {
+	if album.Status == albumType.StatusModerated && !album.IsHidden {
+		return true, nil
+	}
+	if userRole >= auth.RoleModerator {
+		return true, nil
 	}
-	if res.DeletedCount < 1 {
-		return service.ErrNotFound
+	if userID == "" {
+		return false, nil
 	}
-	return nil
+	return s.ownershipService.IsAlbumOwner(ctx, userID, album.ID)
+}
+
+// Delete deletes album with tracks, audio files and cover
+// ownership is checked by ownership middleware
+func (s *AlbumService) Delete(
+	ctx context.Context, albumID string,
+) (*cascade.Deletion, error) {
+	return s.albumDeleter.DeleteAlbum(ctx, albumID)
 }
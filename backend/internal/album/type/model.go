@@ -7,15 +7,24 @@ import (
 )
 
 type AlbumResponse struct {
-	ID        string   `json:"id"`
-	Title     string   `json:"title"`
-	ArtistID  string   `json:"artistID"`
-	Year      int      `json:"year"`
-	CoverPath string   `json:"coverPath"`
-	Genres    []string `json:"genres"`
-	IsHidden  bool     `json:"isHidden"`
-	Status    string   `json:"status"`
-	CreatedAt string   `json:"createdAt"`
+	ID               string   `json:"id"`
+	Title            string   `json:"title"`
+	ArtistID         string   `json:"artistID"`
+	Year             int      `json:"year"`
+	CoverPath        string   `json:"coverPath"`
+	Genres           []string `json:"genres"`
+	IsHidden         bool     `json:"isHidden"`
+	Status           string   `json:"status"`
+	ModerationReason string   `json:"moderationReason,omitempty"`
+	CreatedAt        string   `json:"createdAt"`
+	UpdatedAt        string   `json:"updatedAt"`
+}
+
+type AlbumListResponse struct {
+	Items []AlbumResponse `json:"items"`
+	Total int64           `json:"total"`
+	Page  int64           `json:"page"`
+	Limit int64           `json:"limit"`
 }
 
 type AlbumCreateRequest struct {
@@ -26,16 +35,23 @@ type AlbumCreateRequest struct {
 }
 
 type AlbumUpdateRequest struct {
-	Title  *string  `json:"title" validate:"omitempty,min=3,max=255"`
-	Year   *int     `json:"year" validate:"omitempty,year"`
-	Status *string  `json:"status" validate:"omitempty,status"`
-	Genres []string `json:"genres" validate:"required,genres"`
+	Title    *string  `json:"title" validate:"omitempty,min=3,max=255"`
+	Year     *int     `json:"year" validate:"omitempty,year"`
+	IsHidden *bool    `json:"isHidden"`
+	Genres   []string `json:"genres" validate:"omitempty,genres"`
+}
+
+type ModerationRequest struct {
+	Status string `json:"status" validate:"required,status"`
+	// reason is required if album is denied
+	Reason string `json:"reason" validate:"required_if=Status Denied,max=1024"`
 }
 
 var (
+	// statuses that moderator can set
 	statusValidation = map[string]bool{
-		StatusModerated:    true,
-		StatusOnModeration: true,
+		StatusModerated: true,
+		StatusDenied:    true,
 	}
 )
 
@@ -51,14 +67,16 @@ func ValidateStatus(fl validator.FieldLevel) bool {
 
 func (a *Album) ToResponse() AlbumResponse {
 	return AlbumResponse{
-		ID:        a.ID,
-		Title:     a.Title,
-		ArtistID:  a.ArtistID,
-		Year:      a.Year,
-		CoverPath: a.CoverPath,
-		Genres:    a.Genres,
-		Status:    a.Status,
-		IsHidden:  a.IsHidden,
-		CreatedAt: a.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		ID:               a.ID,
+		Title:            a.Title,
+		ArtistID:         a.ArtistID,
+		Year:             a.Year,
+		CoverPath:        a.CoverPath,
+		Genres:           a.Genres,
+		Status:           a.Status,
+		ModerationReason: a.ModerationReason,
+		IsHidden:         a.IsHidden,
+		CreatedAt:        a.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:        a.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
 }
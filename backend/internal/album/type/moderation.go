@@ -0,0 +1,68 @@
+package albumType
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// ModerationRecord is an entry of album moderation history
+type ModerationRecord struct {
+	ID      string `bson:"id"`
+	AlbumID string `bson:"albumID"`
+	// user who changed status (album owner or moderator)
+	UserID    string    `bson:"userID"`
+	Status    string    `bson:"status"`
+	Reason    string    `bson:"reason"`
+	CreatedAt time.Time `bson:"createdAt"`
+}
+
+type ModerationRecordResponse struct {
+	ID        string `json:"id"`
+	UserID    string `json:"userID"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason,omitempty"`
+	CreatedAt string `json:"createdAt"`
+}
+
+type ModerationHistoryResponse struct {
+	AlbumID string                     `json:"albumID"`
+	Status  string                     `json:"status"`
+	Reason  string                     `json:"reason,omitempty"`
+	History []ModerationRecordResponse `json:"history"`
+}
+
+func (m *ModerationRecord) ToResponse() ModerationRecordResponse {
+	return ModerationRecordResponse{
+		ID:        m.ID,
+		UserID:    m.UserID,
+		Status:    m.Status,
+		Reason:    m.Reason,
+		CreatedAt: m.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+func EnsureModerationIndexes(
+	ctx context.Context, col *mongo.Collection,
+) error {
+	// index for fast history lookup
+	albumIDIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "albumID", Value: 1},
+			{Key: "createdAt", Value: -1},
+		},
+		Options: options.Index().SetName("albumID_createdAt_index"),
+	}
+
+	// unique index by id string
+	idIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("id_unique"),
+	}
+
+	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{albumIDIndex, idIndex})
+	return err
+}
@@ -0,0 +1,46 @@
+package albumType
+
+const (
+	// album matches genres user likes
+	ReasonTaste = "taste"
+	// user has no listening history, newest albums are recommended
+	ReasonNewRelease = "newRelease"
+)
+
+// Recommendation is an album picked for user with explanation
+type Recommendation struct {
+	Album         Album    `bson:"album"`
+	Score         float64  `bson:"score"`
+	MatchedGenres []string `bson:"matchedGenres"`
+	Reason        string   `bson:"-"`
+	Explanation   string   `bson:"-"`
+}
+
+type RecommendationResponse struct {
+	Album         AlbumResponse `json:"album"`
+	Score         float64       `json:"score"`
+	Reason        string        `json:"reason"`
+	MatchedGenres []string      `json:"matchedGenres"`
+	Explanation   string        `json:"explanation"`
+}
+
+type RecommendationListResponse struct {
+	Items []RecommendationResponse `json:"items"`
+	Total int64                    `json:"total"`
+	Page  int64                    `json:"page"`
+	Limit int64                    `json:"limit"`
+}
+
+func (r *Recommendation) ToResponse() RecommendationResponse {
+	matched := r.MatchedGenres
+	if matched == nil {
+		matched = []string{}
+	}
+	return RecommendationResponse{
+		Album:         r.Album.ToResponse(),
+		Score:         r.Score,
+		Reason:        r.Reason,
+		MatchedGenres: matched,
+		Explanation:   r.Explanation,
+	}
+}
@@ -10,15 +10,17 @@ import (
 )
 
 type Album struct {
-	ID        string    `bson:"id"`
-	Title     string    `bson:"title"`
-	ArtistID  string    `bson:"artistID"`
-	Year      int       `bson:"year"`
-	CoverPath string    `bson:"coverPath"`
-	Genres    []string  `bson:"genres"`
-	Status    string    `bson:"status"`
-	IsHidden  bool      `bson:"isHidden"`
-	CreatedAt time.Time `bson:"createdAt"`
+	ID               string    `bson:"id"`
+	Title            string    `bson:"title"`
+	ArtistID         string    `bson:"artistID"`
+	Year             int       `bson:"year"`
+	CoverPath        string    `bson:"coverPath"`
+	Genres           []string  `bson:"genres"`
+	Status           string    `bson:"status"`
+	ModerationReason string    `bson:"moderationReason"`
+	IsHidden         bool      `bson:"isHidden"`
+	CreatedAt        time.Time `bson:"createdAt"`
+	UpdatedAt        time.Time `bson:"updatedAt"`
 }
 
 const (
@@ -44,6 +46,41 @@ func EnsureIndexes(
 		Options: options.Index().SetUnique(true).SetName("id_unique"),
 	}
 
-	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{artistIDTitleIndex, idIndex})
+	// text index for search by title
+	titleTextIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "title", Value: "text"}},
+		Options: options.Index().SetName("title_text"),
+	}
+
+	// cover visibility is checked by cover path
+	coverPathIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "coverPath", Value: 1}},
+		Options: options.Index().SetName("cover_path"),
+	}
+
+	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		artistIDTitleIndex, idIndex, titleTextIndex, coverPathIndex,
+	})
 	return err
 }
+
+// VisibilityFilter returns filter of albums visible to user:
+// moderated and not hidden albums are visible to everyone,
+// albums of own artists are visible to their owner.
+// prefix is used when album is joined into another document (e.g. "album.")
+func VisibilityFilter(prefix string, ownArtistIDs []string) bson.M {
+	public := bson.M{
+		prefix + "isHidden": false,
+		prefix + "status":   StatusModerated,
+	}
+	if len(ownArtistIDs) == 0 {
+		return public
+	}
+
+	return bson.M{
+		"$or": bson.A{
+			public,
+			bson.M{prefix + "artistID": bson.M{"$in": ownArtistIDs}},
+		},
+	}
+}
@@ -1,40 +1,55 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
-	"path"
+	"os/signal"
+	"syscall"
+	"time"
 	"tracker-backend/internal/app/dependencies"
+	authMiddleware "tracker-backend/internal/auth/middleware"
 	"tracker-backend/internal/config"
+	"tracker-backend/internal/pkg/clientip"
+	"tracker-backend/internal/pkg/jobs"
+	"tracker-backend/internal/pkg/storage"
 	"tracker-backend/internal/server"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
+// time to finish requests and jobs on shutdown
+const shutdownTimeout = 30 * time.Second
+
 type App struct {
 	router *chi.Mux
 	port   string
+	jobs   *jobs.Runner
 }
 
-func NewApp(port string, deps *dependencies.Dependencies) *App {
+func NewApp(port string, deps *dependencies.Dependencies, locker jobs.Locker) *App {
 	master := chi.NewRouter()
 
+	// proxies are validated on config load
+	trustedProxies, _ := clientip.ParseProxies(deps.Config.TrustedProxies)
+
 	// use some middleware stack
+	master.Use(clientip.RealIP(trustedProxies))
 	master.Use(middleware.Logger)
-	master.Use(middleware.RealIP)
 	master.Use(middleware.Recoverer)
 
 	// permit access to public images
-	avatarsFS := http.FileServer(http.Dir(
-		path.Join(os.Getenv(config.PublicDirPathEnvName), config.AvatarsDir),
-	))
-	coversFS := http.FileServer(http.Dir(
-		path.Join(os.Getenv(config.PublicDirPathEnvName), config.CoversDir),
-	))
+	avatarsFS := storage.BlobHandler(deps.BlobStore, config.AvatarsDir)
+	coversFS := storage.BlobHandler(deps.BlobStore, config.CoversDir)
 	master.Handle("/public/avatars/*", http.StripPrefix("/public/avatars/", avatarsFS))
-	master.Handle("/public/covers/*", http.StripPrefix("/public/covers/", coversFS))
+	// covers of albums not visible to user are not found
+	master.With(
+		authMiddleware.OptionalAuthorization(deps.UserService, deps.SessionService, deps.Config.Secret),
+		authMiddleware.RequireVisibleCover(deps.AlbumService),
+	).Handle("/public/covers/*", http.StripPrefix("/public/covers/", coversFS))
 
 	// mount api routes
 	master.Mount("/api", server.NewAppRouter(deps))
@@ -44,14 +59,43 @@ func NewApp(port string, deps *dependencies.Dependencies) *App {
 	app := &App{
 		router: master,
 		port:   port,
+		jobs:   newJobRunner(deps, locker),
 	}
 
 	return app
 }
 
+// Run starts server and background jobs,
+// both are stopped gracefully on SIGINT or SIGTERM
 func (a *App) Run() {
-	fmt.Printf("server started on address %s\n", a.port)
-	if err := http.ListenAndServe(":"+a.port, a.router); err != nil {
-		fmt.Printf("error occurred %s\n", err.Error())
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{
+		Addr:    ":" + a.port,
+		Handler: a.router,
+	}
+
+	a.jobs.Start()
+
+	go func() {
+		fmt.Printf("server started on address %s\n", a.port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("error occurred %s\n", err.Error())
+		}
+		stop()
+	}()
+
+	<-ctx.Done()
+	fmt.Println("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		fmt.Printf("failed to shutdown server %s\n", err.Error())
+	}
+	if err := a.jobs.Stop(shutdownCtx); err != nil {
+		fmt.Printf("failed to stop jobs %s\n", err.Error())
 	}
 }
@@ -3,51 +3,153 @@ package dependencies
 import (
 	"context"
 	"tracker-backend/internal/album"
+	albumModeration "tracker-backend/internal/album/moderation"
+	albumRecommendations "tracker-backend/internal/album/recommendations"
 	albumTracks "tracker-backend/internal/album/tracks"
 	"tracker-backend/internal/app/repository"
 	"tracker-backend/internal/artist"
 	artistAlbums "tracker-backend/internal/artist/albums"
+	artistStats "tracker-backend/internal/artist/stats"
+	"tracker-backend/internal/auth/lockout"
+	"tracker-backend/internal/auth/onetime"
 	"tracker-backend/internal/auth/ownership"
+	"tracker-backend/internal/auth/session"
+	"tracker-backend/internal/cascade"
+	"tracker-backend/internal/config"
+	"tracker-backend/internal/genre"
+	"tracker-backend/internal/hls"
+	"tracker-backend/internal/listen"
+	"tracker-backend/internal/media"
+	"tracker-backend/internal/pkg/mailer"
+	"tracker-backend/internal/pkg/storage"
 	"tracker-backend/internal/playlist"
+	playlistRecommendations "tracker-backend/internal/playlist/recommendations"
+	playlistTracks "tracker-backend/internal/playlist/tracks"
+	"tracker-backend/internal/search"
 	"tracker-backend/internal/track"
 	"tracker-backend/internal/user"
+	userHistory "tracker-backend/internal/user/history"
+	userVerification "tracker-backend/internal/user/verification"
 )
 
 type Dependencies struct {
+	*ownership.OwnershipService
+	*session.SessionService
+	*lockout.LoginLockoutService
 	*user.UserService
+	*userHistory.UserHistoryService
+	*userVerification.UserVerificationService
 	*artist.ArtistService
 	*artistAlbums.ArtistAlbumsService
+	*artistStats.ArtistStatsService
 	*track.TrackService
 	*album.AlbumService
 	*albumTracks.AlbumTracksService
+	*albumModeration.AlbumModerationService
+	*albumRecommendations.AlbumRecommendationsService
 	*playlist.PlaylistService
+	*playlistTracks.PlaylistTracksService
+	*playlistRecommendations.PlaylistRecommendationsService
+	*search.SearchService
+	*genre.GenreModelService
+	*listen.ListenService
+	*cascade.CascadeService
+	*media.MediaGCService
+	*hls.HLSService
+
+	Config    *config.Config
+	BlobStore storage.BlobStore
+	// rate limiter of api routes
+	RateLimiter *storage.RedisClient
 }
 
 func InitDependencies(
-	ctx context.Context, repo *repository.Repository,
+	ctx context.Context, cfg *config.Config,
+	repo *repository.Repository, redis *storage.RedisClient,
+	blobStore storage.BlobStore, mailer mailer.Mailer,
 ) *Dependencies {
 	ownershipService := ownership.NewOwnershipService(
 		repo.AlbumsCollection, repo.ArtistsCollection,
+		repo.TracksCollection, repo.PlaylistsCollection,
 	)
 
-	playlistService := playlist.NewPlaylistService(repo.PlaylistsCollection)
+	genreModelService := genre.NewGenreModelService(repo.TracksCollection, redis)
+	playlistService := playlist.NewPlaylistService(repo.PlaylistsCollection, repo.TracksCollection, genreModelService)
+	playlistTracksService := playlistTracks.NewPlaylistTracksService(repo.PlaylistsCollection, repo.ArtistsCollection)
+	playlistRecommendationsService := playlistRecommendations.NewPlaylistRecommendationsService(
+		repo.TracksCollection, repo.AlbumsCollection,
+		repo.ArtistsCollection, repo.PlaylistsCollection,
+		playlistService,
+	)
+	cascadeService := cascade.NewCascadeService(
+		repo.UsersCollection, repo.ArtistsCollection, repo.AlbumsCollection, repo.TracksCollection,
+		repo.PlaylistsCollection, repo.ModerationsCollection, repo.ListensCollection, repo.DeletionsCollection,
+		genreModelService, blobStore,
+	)
+	sessionService := session.NewSessionService(redis, cfg.Secret)
+	loginLockoutService := lockout.NewLoginLockoutService(redis)
+	userVerificationService := userVerification.NewUserVerificationService(
+		repo.UsersCollection, onetime.NewTokenService(redis, cfg.Secret), mailer, sessionService,
+		cfg.AppURL, cfg.RequireVerifiedEmail,
+	)
 	userService := user.NewUserService(
 		ctx, repo.UsersCollection,
-		playlistService,
+		playlistService, cascadeService, sessionService, userVerificationService,
+		loginLockoutService,
 	)
+	userHistoryService := userHistory.NewUserHistoryService(repo.ListensCollection)
+	listenService := listen.NewListenService(repo.ListensCollection, repo.TracksCollection, redis, genreModelService)
 	artistAlbumsService := artistAlbums.NewArtistAlbumsService(repo.AlbumsCollection, ownershipService)
-	artistService := artist.NewArtistService(repo.ArtistsCollection)
+	artistService := artist.NewArtistService(repo.ArtistsCollection, cascadeService, blobStore)
+	artistStatsService := artistStats.NewArtistStatsService(
+		repo.AlbumsCollection, repo.TracksCollection,
+		repo.ListensCollection, repo.PlaylistsCollection,
+		redis,
+	)
 	albumTracksService := albumTracks.NewAlbumTracksService(repo.TracksCollection, repo.AlbumsCollection, ownershipService)
-	albumService := album.NewAlbumService(repo.AlbumsCollection, albumTracksService, ownershipService)
-	trackService := track.NewTrackService(repo.TracksCollection, ownershipService, albumService)
+	albumModerationService := albumModeration.NewAlbumModerationService(
+		repo.AlbumsCollection, repo.ModerationsCollection,
+	)
+	albumRecommendationsService := albumRecommendations.NewAlbumRecommendationsService(
+		repo.AlbumsCollection, repo.ArtistsCollection, repo.PlaylistsCollection,
+		genreModelService,
+	)
+	albumService := album.NewAlbumService(repo.AlbumsCollection, albumTracksService, albumModerationService, ownershipService, cascadeService, blobStore)
+	trackService := track.NewTrackService(repo.TracksCollection, ownershipService, albumService, blobStore, cfg.Secret)
+	searchService := search.NewSearchService(repo.TracksCollection, repo.AlbumsCollection, repo.ArtistsCollection)
+	mediaGCService := media.NewMediaGCService(
+		repo.TracksCollection, repo.AlbumsCollection, repo.ArtistsCollection,
+		blobStore, cfg.MediaGC.Mode, cfg.MediaGC.Grace,
+	)
+
+	hlsService := hls.NewHLSService(repo.TracksCollection, blobStore, cfg.HLS.FFmpegPath)
 
 	return &Dependencies{
-		PlaylistService:     playlistService,
-		UserService:         userService,
-		ArtistAlbumsService: artistAlbumsService,
-		ArtistService:       artistService,
-		AlbumTracksService:  albumTracksService,
-		TrackService:        trackService,
-		AlbumService:        albumService,
+		OwnershipService:               ownershipService,
+		SessionService:                 sessionService,
+		LoginLockoutService:            loginLockoutService,
+		PlaylistService:                playlistService,
+		PlaylistTracksService:          playlistTracksService,
+		PlaylistRecommendationsService: playlistRecommendationsService,
+		UserService:                    userService,
+		UserHistoryService:             userHistoryService,
+		UserVerificationService:        userVerificationService,
+		ArtistAlbumsService:            artistAlbumsService,
+		ArtistService:                  artistService,
+		ArtistStatsService:             artistStatsService,
+		AlbumTracksService:             albumTracksService,
+		AlbumModerationService:         albumModerationService,
+		AlbumRecommendationsService:    albumRecommendationsService,
+		TrackService:                   trackService,
+		AlbumService:                   albumService,
+		SearchService:                  searchService,
+		GenreModelService:              genreModelService,
+		ListenService:                  listenService,
+		CascadeService:                 cascadeService,
+		MediaGCService:                 mediaGCService,
+		HLSService:                     hlsService,
+		Config:                         cfg,
+		BlobStore:                      blobStore,
+		RateLimiter:                    redis,
 	}
 }
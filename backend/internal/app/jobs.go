@@ -0,0 +1,55 @@
+package app
+
+import (
+	"context"
+	"time"
+	"tracker-backend/internal/app/dependencies"
+	"tracker-backend/internal/pkg/jobs"
+)
+
+// newJobRunner registers background jobs of app
+func newJobRunner(deps *dependencies.Dependencies, locker jobs.Locker) *jobs.Runner {
+	runner := jobs.NewRunner(locker)
+
+	// remove links to deleted tracks from playlists
+	runner.Add(jobs.Job{
+		Name:     "playlist_links_sweeper",
+		Interval: time.Hour,
+		Timeout:  10 * time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := deps.PlaylistService.RemoveInvalidLinks(ctx)
+			return err
+		},
+	})
+
+	// finish cascade deletions failed midway
+	runner.Add(jobs.Job{
+		Name:     "cascade_deletions_resumer",
+		Interval: 15 * time.Minute,
+		Timeout:  10 * time.Minute,
+		Run:      deps.CascadeService.ResumePending,
+	})
+
+	// remove or quarantine uploaded files nothing refers to
+	runner.Add(jobs.Job{
+		Name:     "media_gc",
+		Interval: 6 * time.Hour,
+		Timeout:  30 * time.Minute,
+		Run: func(ctx context.Context) error {
+			_, err := deps.MediaGCService.Collect(ctx)
+			return err
+		},
+	})
+
+	// transcode uploaded tracks to hls, tracks are claimed
+	// one by one so every instance can run transcoder
+	runner.Add(jobs.Job{
+		Name:     "hls_transcoder",
+		Interval: 30 * time.Second,
+		Timeout:  30 * time.Minute,
+		Run:      deps.HLSService.TranscodePending,
+		Parallel: true,
+	})
+
+	return runner
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	albumType "tracker-backend/internal/album/type"
 	artistType "tracker-backend/internal/artist/type"
+	"tracker-backend/internal/cascade"
+	listenType "tracker-backend/internal/listen/type"
 	playlistType "tracker-backend/internal/playlist/type"
 	"tracker-backend/internal/track"
 	userType "tracker-backend/internal/user/type"
@@ -14,11 +16,14 @@ import (
 // TODO: setup collection and ensure indices
 
 type Repository struct {
-	PlaylistsCollection *mongo.Collection
-	UsersCollection     *mongo.Collection
-	ArtistsCollection   *mongo.Collection
-	AlbumsCollection    *mongo.Collection
-	TracksCollection    *mongo.Collection
+	PlaylistsCollection   *mongo.Collection
+	UsersCollection       *mongo.Collection
+	ArtistsCollection     *mongo.Collection
+	AlbumsCollection      *mongo.Collection
+	TracksCollection      *mongo.Collection
+	ModerationsCollection *mongo.Collection
+	ListensCollection     *mongo.Collection
+	DeletionsCollection   *mongo.Collection
 }
 
 func MustInitRepository(ctx context.Context, db *mongo.Database) *Repository {
@@ -28,6 +33,9 @@ func MustInitRepository(ctx context.Context, db *mongo.Database) *Repository {
 	artistsCollection := db.Collection("artists")
 	albumsCollection := db.Collection("albums")
 	tracksCollection := db.Collection("tracks")
+	moderationsCollection := db.Collection("moderations")
+	listensCollection := db.Collection("listens")
+	deletionsCollection := db.Collection("deletions")
 
 	// ensure albums indices
 	if err := albumType.EnsureIndexes(ctx, albumsCollection); err != nil {
@@ -37,7 +45,7 @@ func MustInitRepository(ctx context.Context, db *mongo.Database) *Repository {
 	if err := track.EnsureIndexes(ctx, tracksCollection); err != nil {
 		panic(err.Error())
 	}
-	// ensure tracks indices
+	// ensure artists indices
 	if err := artistType.EnsureIndexes(ctx, artistsCollection); err != nil {
 		panic(err.Error())
 	}
@@ -45,16 +53,35 @@ func MustInitRepository(ctx context.Context, db *mongo.Database) *Repository {
 	if err := userType.EnsureIndexes(ctx, usersCollection); err != nil {
 		panic(err.Error())
 	}
+	// migrate users saved before default playlist field was renamed
+	if err := userType.MigrateDefaultPlaylistField(ctx, usersCollection); err != nil {
+		panic(err.Error())
+	}
 	// ensure playlists indices
 	if err := playlistType.EnsureIndexes(ctx, playlistsCollection); err != nil {
 		panic(err.Error())
 	}
+	// ensure album moderation history indices
+	if err := albumType.EnsureModerationIndexes(ctx, moderationsCollection); err != nil {
+		panic(err.Error())
+	}
+	// ensure listens indices
+	if err := listenType.EnsureIndexes(ctx, listensCollection); err != nil {
+		panic(err.Error())
+	}
+	// ensure cascade deletions indices
+	if err := cascade.EnsureIndexes(ctx, deletionsCollection); err != nil {
+		panic(err.Error())
+	}
 
 	return &Repository{
-		PlaylistsCollection: playlistsCollection,
-		UsersCollection:     usersCollection,
-		ArtistsCollection:   artistsCollection,
-		AlbumsCollection:    albumsCollection,
-		TracksCollection:    tracksCollection,
+		PlaylistsCollection:   playlistsCollection,
+		UsersCollection:       usersCollection,
+		ArtistsCollection:     artistsCollection,
+		AlbumsCollection:      albumsCollection,
+		TracksCollection:      tracksCollection,
+		ModerationsCollection: moderationsCollection,
+		ListensCollection:     listensCollection,
+		DeletionsCollection:   deletionsCollection,
 	}
 }
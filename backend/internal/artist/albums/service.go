@@ -6,6 +6,7 @@ import (
 	albumType "tracker-backend/internal/album/type"
 	"tracker-backend/internal/auth"
 	"tracker-backend/internal/auth/ownership"
+	"tracker-backend/internal/pkg/service"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
@@ -40,6 +41,9 @@ func (s *ArtistAlbumsService) GetByArtistID(
 		"artistID": artistID,
 	}
 	isOwn, err := s.ownershipService.IsArtistOwner(ctx, userID, artistID)
+	if err != nil && !errors.Is(err, service.ErrNotFound) {
+		return nil, errors.New("failed to check ownership")
+	}
 
 	// if user is not moderator -> show only moderated and public albums
 	if userRole <= auth.RoleCustomer && !isOwn {
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	artistType "tracker-backend/internal/artist/type"
 	"tracker-backend/internal/auth"
+	"tracker-backend/internal/auth/ownership"
 	"tracker-backend/internal/pkg/response"
 	"tracker-backend/internal/pkg/service"
 
@@ -60,36 +61,30 @@ func (h *ArtistHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 func (h *ArtistHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	userID := ctx.Value(auth.UserIDKey).(string)
 
 	// get url ID param
 	artistID := chi.URLParam(r, "id")
-	if artistID == "" {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, response.Error("missing artist id"))
-		return
-	}
 
-	if err := h.Service.Delete(ctx, artistID, userID); err != nil {
-		render.Status(r, http.StatusNotFound)
+	deletion, err := h.Service.Delete(ctx, artistID)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			render.Status(r, http.StatusNotFound)
+		} else {
+			render.Status(r, http.StatusInternalServerError)
+		}
 		render.JSON(w, r, response.Error(err.Error()))
 		return
 	}
 
-	render.Status(r, http.StatusNoContent)
+	// send deletion report
+	render.JSON(w, r, deletion.ToResponse())
 }
 
 func (h *ArtistHandler) Update(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	userID := ctx.Value(auth.UserIDKey).(string)
 
 	// get url ID param
 	artistID := chi.URLParam(r, "id")
-	if artistID == "" {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, response.Error("missing artist id"))
-		return
-	}
 
 	var req artistType.UpdateRequest
 	if err := render.DecodeJSON(r.Body, &req); err != nil {
@@ -98,13 +93,7 @@ func (h *ArtistHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.AvatarPath != nil {
-		render.Status(r, http.StatusMethodNotAllowed)
-		render.JSON(w, r, response.Error("use PUT /api/artist/{id}/avatar to update artist avatar"))
-		return
-	}
-
-	artist, err := h.Service.Update(ctx, artistID, userID, req)
+	artist, err := h.Service.Update(ctx, artistID, req)
 	// check update results
 	if err != nil {
 		if errors.Is(err, ErrNameTaken) {
@@ -122,13 +111,12 @@ func (h *ArtistHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 func (h *ArtistHandler) UpdateAvatar(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	userID := ctx.Value(auth.UserIDKey).(string)
 
-	// get url ID param
-	artistID := chi.URLParam(r, "id")
-	if artistID == "" {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, response.Error("missing artist id"))
+	// get artist loaded by ownership middleware
+	var artist artistType.Artist
+	if err := ownership.ResourceFromContext(ctx, &artist); err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error("failed to get artist"))
 		return
 	}
 
@@ -148,8 +136,8 @@ func (h *ArtistHandler) UpdateAvatar(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// call update function
-	artist, err := h.Service.UpdateAvatar(
-		ctx, userID, artistID, &file, fileHeader,
+	updated, err := h.Service.UpdateAvatar(
+		ctx, &artist, &file, fileHeader,
 	)
 	if err != nil {
 		render.Status(r, http.StatusNotFound)
@@ -158,7 +146,7 @@ func (h *ArtistHandler) UpdateAvatar(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// send updated artist
-	render.JSON(w, r, artist)
+	render.JSON(w, r, updated)
 }
 
 func (h *ArtistHandler) GetByID(w http.ResponseWriter, r *http.Request) {
@@ -2,7 +2,9 @@ package artist
 
 import (
 	artistAlbums "tracker-backend/internal/artist/albums"
+	artistStats "tracker-backend/internal/artist/stats"
 	"tracker-backend/internal/auth"
+	"tracker-backend/internal/auth/ownership"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -10,10 +12,14 @@ import (
 func RegisterArtistRoutes(
 	r chi.Router,
 	service *ArtistService, artistAlbumsService *artistAlbums.ArtistAlbumsService,
+	artistStatsService *artistStats.ArtistStatsService,
+	ownershipService *ownership.OwnershipService,
 	authMiddleware auth.MiddlewareFunc,
+	uploadMiddleware auth.MiddlewareFunc,
 ) {
 	h := NewArtistHandler(service)
 	ha := artistAlbums.NewArtistAlbumsHandler(artistAlbumsService)
+	hs := artistStats.NewArtistStatsHandler(artistStatsService)
 
 	r.Route("/artist", func(r chi.Router) {
 
@@ -30,9 +36,18 @@ func RegisterArtistRoutes(
 			r.Use(authMiddleware)
 
 			r.Get("/{id}/albums", ha.GetAlbums)
-			r.Put("/{id}", h.Update)
-			r.Put("/{id}/avatar", h.UpdateAvatar)
-			r.Delete("/{id}", h.Delete)
+
+			// owner only
+			r.Group(func(r chi.Router) {
+				r.Use(ownershipService.Require(ownership.ResourceArtist, ownership.NoBypass))
+				r.Put("/{id}", h.Update)
+				r.With(uploadMiddleware).Put("/{id}/avatar", h.UpdateAvatar)
+				r.Get("/{id}/stats", hs.GetStats)
+			})
+
+			// owner or admin
+			r.With(ownershipService.Require(ownership.ResourceArtist, auth.RoleAdmin)).
+				Delete("/{id}", h.Delete)
 		})
 
 	})
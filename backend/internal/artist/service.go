@@ -4,15 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"mime/multipart"
-	"os"
 	"path"
-	"strings"
 	"time"
 	artistType "tracker-backend/internal/artist/type"
+	"tracker-backend/internal/cascade"
 	"tracker-backend/internal/config"
 	uploadfile "tracker-backend/internal/pkg/file"
 	"tracker-backend/internal/pkg/service"
+	"tracker-backend/internal/pkg/storage"
 
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
@@ -25,12 +26,21 @@ var (
 )
 
 type ArtistService struct {
-	Col *mongo.Collection
+	Col           *mongo.Collection
+	artistDeleter ArtistDeleter
+	blobStore     storage.BlobStore
+}
+
+// ArtistDeleter removes artist with its albums, tracks and files
+type ArtistDeleter interface {
+	DeleteArtist(ctx context.Context, artistID string) (*cascade.Deletion, error)
 }
 
 // NewArtistService new artist service instance
-func NewArtistService(artistCol *mongo.Collection) *ArtistService {
-	return &ArtistService{Col: artistCol}
+func NewArtistService(
+	artistCol *mongo.Collection, artistDeleter ArtistDeleter, blobStore storage.BlobStore,
+) *ArtistService {
+	return &ArtistService{Col: artistCol, artistDeleter: artistDeleter, blobStore: blobStore}
 }
 
 // Create new artist from CreateRequest
@@ -45,7 +55,7 @@ func (s *ArtistService) Create(
 		ID:         uuid.New().String(),
 		Name:       req.Name,
 		UserID:     userID,
-		AvatarPath: "public/avatars/avatar_default.jpg",
+		AvatarPath: uploadfile.PublicPath(path.Join(config.AvatarsDir, "avatar_default.jpg")),
 		CreatedAt:  time.Now(),
 	}
 
@@ -60,28 +70,19 @@ func (s *ArtistService) Create(
 	return artist, nil
 }
 
-// Delete deletes artist
+// Delete deletes artist with albums, tracks and files
+// ownership is checked by ownership middleware
 func (s *ArtistService) Delete(
-	ctx context.Context, artistID, userID string,
-) error {
-	filter := bson.M{"id": artistID, "userID": userID}
-	res, err := s.Col.DeleteOne(ctx, filter)
-	if err != nil {
-		return err
-	}
-
-	// TODO: delete related tracks and albums
-
-	if res.DeletedCount == 0 {
-		return errors.New("artist not found or not owned by the user")
-	}
-	return nil
+	ctx context.Context, artistID string,
+) (*cascade.Deletion, error) {
+	return s.artistDeleter.DeleteArtist(ctx, artistID)
 }
 
 // Update updates artist name
+// ownership is checked by ownership middleware
 func (s *ArtistService) Update(
 	ctx context.Context,
-	artistID string, userID string,
+	artistID string,
 	req artistType.UpdateRequest,
 ) (*artistType.Artist, error) {
 
@@ -89,9 +90,6 @@ func (s *ArtistService) Update(
 	if req.Name != nil {
 		update["name"] = *req.Name
 	}
-	if req.AvatarPath != nil {
-		update["avatarPath"] = *req.AvatarPath
-	}
 
 	// check name uniqueness
 	if name, ok := update["name"]; ok {
@@ -106,7 +104,7 @@ func (s *ArtistService) Update(
 		}
 	}
 
-	filter := bson.M{"id": artistID, "userID": userID}
+	filter := bson.M{"id": artistID}
 
 	var artist artistType.Artist
 	err := s.Col.FindOneAndUpdate(
@@ -126,46 +124,28 @@ func (s *ArtistService) Update(
 }
 
 // UpdateAvatar updates artist avatar from form file
+// artist is loaded by ownership middleware
 func (s *ArtistService) UpdateAvatar(
 	ctx context.Context,
-	userID string, artistID string,
+	artist *artistType.Artist,
 	file *multipart.File, fileHeader *multipart.FileHeader,
 ) (*artistType.Artist, error) {
 	// validate file
 	if err := uploadfile.ValidateFile(fileHeader, uploadfile.AllowedImageExtensions); err != nil {
 		return nil, err
 	}
-	filter := bson.M{"userID": userID, "id": artistID}
-
-	// find artist and decode
-	var artist artistType.Artist
-	err := s.Col.FindOne(ctx, filter).Decode(&artist)
-	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, service.ErrNotFound
-		}
-		return nil, err
-	}
-
-	// check avatar file path
-	if !strings.Contains(artist.AvatarPath, "avatar_default") {
-		// delete old one
-		if _, err := os.Stat(artist.AvatarPath); err == nil {
-			if err := os.Remove(artist.AvatarPath); err != nil {
-				return nil, fmt.Errorf("failed to delete old file")
-			}
-		}
-	}
+	filter := bson.M{"id": artist.ID}
 
 	// upload new file
-	fileDir := path.Join(os.Getenv(config.PublicDirPathEnvName), config.AvatarsDir)
-	newFilePath, err := uploadfile.UploadFile(fileHeader, file, fileDir, uploadfile.AllowedImageExtensions)
+	newKey, err := uploadfile.UploadFile(
+		ctx, s.blobStore, fileHeader, file, config.AvatarsDir, uploadfile.AllowedImageExtensions,
+	)
 	if err != nil {
 		return nil, err
 	}
 
 	// create new update data
-	update := bson.M{"avatarPath": newFilePath}
+	update := bson.M{"avatarPath": uploadfile.PublicPath(newKey)}
 
 	var updatedArtist artistType.Artist
 	err = s.Col.FindOneAndUpdate(
@@ -176,9 +156,20 @@ func (s *ArtistService) UpdateAvatar(
 	).Decode(&updatedArtist)
 
 	if err != nil {
+		s.blobStore.Delete(ctx, newKey)
 		return nil, fmt.Errorf("failed to update")
 	}
 
+	// delete old avatar after update, default one is shared by artists
+	if key, ok := uploadfile.UploadedKey(artist.AvatarPath, config.AvatarsDir); ok {
+		if err := s.blobStore.Delete(ctx, key); err != nil {
+			slog.Warn("failed to delete old avatar",
+				slog.String("function", "artist.ArtistService.UpdateAvatar"),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+
 	return &updatedArtist, nil
 }
 
@@ -0,0 +1,106 @@
+package artistStats
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+	artistType "tracker-backend/internal/artist/type"
+	"tracker-backend/internal/auth/ownership"
+	"tracker-backend/internal/pkg/response"
+
+	"github.com/go-chi/render"
+)
+
+const (
+	// default period of statistics
+	defaultStatsDays = 30
+	// max period of statistics
+	maxStatsDays = 366
+)
+
+type ArtistStatsHandler struct {
+	Service *ArtistStatsService
+}
+
+func NewArtistStatsHandler(s *ArtistStatsService) *ArtistStatsHandler {
+	return &ArtistStatsHandler{
+		Service: s,
+	}
+}
+
+// GET /artist/{id}/stats?bucket&from&to
+func (h *ArtistStatsHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// get artist loaded by ownership middleware
+	var artist artistType.Artist
+	if err := ownership.ResourceFromContext(ctx, &artist); err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error("failed to get artist"))
+		return
+	}
+
+	params, err := statsParamsFromRequest(r)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
+
+	// execute service function
+	stats, err := h.Service.GetStats(ctx, artist.ID, params)
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
+
+	// send response
+	render.JSON(w, r, stats)
+}
+
+// statsParamsFromRequest reads 'bucket', 'from' and 'to' query params,
+// by default stats of the last 30 days by day are returned
+func statsParamsFromRequest(r *http.Request) (StatsParams, error) {
+	query := r.URL.Query()
+	now := time.Now().UTC()
+	params := StatsParams{
+		Bucket: artistType.BucketDay,
+		To:     time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+	}
+
+	if bucket := query.Get("bucket"); bucket != "" {
+		buckets := []string{artistType.BucketDay, artistType.BucketWeek, artistType.BucketMonth}
+		if !slices.Contains(buckets, bucket) {
+			return params, errors.New("bucket must be one of day, week, month")
+		}
+		params.Bucket = bucket
+	}
+
+	if to := query.Get("to"); to != "" {
+		t, err := time.Parse(dateLayout, to)
+		if err != nil {
+			return params, errors.New("invalid 'to' date, expected YYYY-MM-DD")
+		}
+		params.To = t
+	}
+
+	params.From = params.To.AddDate(0, 0, -(defaultStatsDays - 1))
+	if from := query.Get("from"); from != "" {
+		t, err := time.Parse(dateLayout, from)
+		if err != nil {
+			return params, errors.New("invalid 'from' date, expected YYYY-MM-DD")
+		}
+		params.From = t
+	}
+
+	if params.From.After(params.To) {
+		return params, errors.New("'from' must not be after 'to'")
+	}
+	if params.To.Sub(params.From) >= maxStatsDays*24*time.Hour {
+		return params, errors.New("period must not be longer than 366 days")
+	}
+
+	return params, nil
+}
@@ -0,0 +1,488 @@
+package artistStats
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"sort"
+	"time"
+	albumType "tracker-backend/internal/album/type"
+	artistType "tracker-backend/internal/artist/type"
+	"tracker-backend/internal/pkg/storage"
+	"tracker-backend/internal/track"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	statsKeyPrefix = "artist_stats:"
+	statsCacheTTL  = 10 * time.Minute
+
+	// number of genres in listener genres top
+	topGenresCount = 10
+
+	dateLayout = "2006-01-02"
+)
+
+type ArtistStatsService struct {
+	albumsCol    *mongo.Collection
+	tracksCol    *mongo.Collection
+	listensCol   *mongo.Collection
+	playlistsCol *mongo.Collection
+	redis        *storage.RedisClient
+}
+
+func NewArtistStatsService(
+	albumsCol, tracksCol, listensCol, playlistsCol *mongo.Collection,
+	redis *storage.RedisClient,
+) *ArtistStatsService {
+	return &ArtistStatsService{
+		albumsCol:    albumsCol,
+		tracksCol:    tracksCol,
+		listensCol:   listensCol,
+		playlistsCol: playlistsCol,
+		redis:        redis,
+	}
+}
+
+// StatsParams is a period of statistics, both days are included
+type StatsParams struct {
+	Bucket string
+	From   time.Time
+	To     time.Time
+}
+
+// playsKey is a group of listens, fields not used in grouping are empty
+type playsKey struct {
+	ID     string    `bson:"id"` // track or album id
+	Bucket time.Time `bson:"bucket"`
+}
+
+// playsGroup is listens grouped by track, album or time bucket
+type playsGroup struct {
+	Key       playsKey `bson:"_id"`
+	Plays     int64    `bson:"plays"`
+	Completed int64    `bson:"completed"`
+	Listeners int64    `bson:"listeners"`
+}
+
+type listensResult struct {
+	Totals        []playsGroup
+	Timeline      []playsGroup
+	Tracks        []playsGroup
+	Albums        []playsGroup
+	TrackTimeline []playsGroup
+	AlbumTimeline []playsGroup
+}
+
+// GetStats returns plays, listeners, playlist adds and listener genres
+// of artist tracks for period. Result is cached in redis
+func (s *ArtistStatsService) GetStats(
+	ctx context.Context, artistID string, params StatsParams,
+) (*artistType.StatsResponse, error) {
+	// configure logger
+	logger := slog.With(slog.String("function", "artistStats.ArtistStatsService.GetStats"))
+
+	from, to := params.From.Format(dateLayout), params.To.Format(dateLayout)
+	key := statsKeyPrefix + artistID + ":" + params.Bucket + ":" + from + ":" + to
+
+	// try to get cached stats
+	var cached artistType.StatsResponse
+	if err := s.redis.GetJSON(ctx, key, &cached); err == nil {
+		return &cached, nil
+	}
+
+	// get artist albums and tracks
+	var albums []albumType.Album
+	cur, err := s.albumsCol.Find(ctx,
+		bson.M{"artistID": artistID},
+		options.Find().SetProjection(bson.M{"id": 1, "title": 1}).SetSort(bson.M{"createdAt": 1}),
+	)
+	if err != nil || cur.All(ctx, &albums) != nil {
+		return nil, errors.New("failed to get artist albums")
+	}
+	albumIDs := make([]string, len(albums))
+	for i, a := range albums {
+		albumIDs[i] = a.ID
+	}
+
+	var tracks []track.Track
+	cur, err = s.tracksCol.Find(ctx,
+		bson.M{"album": bson.M{"$in": albumIDs}},
+		options.Find().SetProjection(bson.M{"id": 1, "title": 1, "album": 1}).SetSort(bson.M{"createdAt": 1}),
+	)
+	if err != nil || cur.All(ctx, &tracks) != nil {
+		return nil, errors.New("failed to get artist tracks")
+	}
+	trackIDs := make([]string, len(tracks))
+	for i, t := range tracks {
+		trackIDs[i] = t.ID
+	}
+
+	// period of listens, the last day is included
+	period := bson.M{
+		"$gte": params.From,
+		"$lt":  params.To.AddDate(0, 0, 1),
+	}
+
+	listens, err := s.aggregateListens(ctx, albumIDs, period, params.Bucket)
+	if err != nil {
+		logger.Warn("failed to aggregate listens", slog.String("error", err.Error()))
+		return nil, errors.New("failed to get listens")
+	}
+
+	playlistAdds, err := s.countPlaylistAdds(ctx, trackIDs)
+	if err != nil {
+		logger.Warn("failed to aggregate playlists", slog.String("error", err.Error()))
+		return nil, errors.New("failed to get playlist adds")
+	}
+
+	genres, err := s.aggregateListenerGenres(ctx, albumIDs, period)
+	if err != nil {
+		logger.Warn("failed to aggregate listener genres", slog.String("error", err.Error()))
+		return nil, errors.New("failed to get listener genres")
+	}
+
+	stats := &artistType.StatsResponse{
+		ArtistID:          artistID,
+		Bucket:            params.Bucket,
+		From:              from,
+		To:                to,
+		Timeline:          timeline(listens.Timeline, params),
+		Tracks:            make([]artistType.TrackStats, len(tracks)),
+		Albums:            make([]artistType.AlbumStats, len(albums)),
+		TopListenerGenres: genres,
+		CachedAt:          time.Now().Format(time.RFC3339),
+	}
+
+	// totals
+	if len(listens.Totals) > 0 {
+		t := listens.Totals[0]
+		stats.Totals = artistType.StatsTotals{
+			Plays:           t.Plays,
+			CompletedPlays:  t.Completed,
+			CompletionRate:  rate(t.Completed, t.Plays),
+			UniqueListeners: t.Listeners,
+		}
+	}
+
+	// per track stats
+	trackPlays := groupsByID(listens.Tracks)
+	trackTimelines := splitByID(listens.TrackTimeline)
+	albumAdds := map[string]int64{}
+	for i, t := range tracks {
+		p := trackPlays[t.ID]
+		stats.Tracks[i] = artistType.TrackStats{
+			TrackID:         t.ID,
+			Title:           t.Title,
+			AlbumID:         t.AlbumID,
+			Plays:           p.Plays,
+			CompletionRate:  rate(p.Completed, p.Plays),
+			UniqueListeners: p.Listeners,
+			PlaylistAdds:    playlistAdds[t.ID],
+			Timeline:        timeline(trackTimelines[t.ID], params),
+		}
+		albumAdds[t.AlbumID] += playlistAdds[t.ID]
+		stats.Totals.PlaylistAdds += playlistAdds[t.ID]
+	}
+
+	// per album stats
+	albumPlays := groupsByID(listens.Albums)
+	albumTimelines := splitByID(listens.AlbumTimeline)
+	for i, a := range albums {
+		p := albumPlays[a.ID]
+		stats.Albums[i] = artistType.AlbumStats{
+			AlbumID:         a.ID,
+			Title:           a.Title,
+			Plays:           p.Plays,
+			CompletionRate:  rate(p.Completed, p.Plays),
+			UniqueListeners: p.Listeners,
+			PlaylistAdds:    albumAdds[a.ID],
+			Timeline:        timeline(albumTimelines[a.ID], params),
+		}
+	}
+
+	// cache stats
+	if err := s.redis.SetJSON(ctx, key, stats, statsCacheTTL); err != nil {
+		logger.Warn("failed to cache stats", slog.String("error", err.Error()))
+	}
+
+	return stats, nil
+}
+
+// aggregateListens groups listens of albums by time bucket, track and album.
+// Every grouping is a separate aggregation, so result size
+// is not limited by size of one document
+func (s *ArtistStatsService) aggregateListens(
+	ctx context.Context, albumIDs []string, period bson.M, bucket string,
+) (*listensResult, error) {
+	match := bson.M{
+		"albumID":   bson.M{"$in": albumIDs},
+		"createdAt": period,
+	}
+	bucketStart := bson.M{"$dateTrunc": bson.M{
+		"date":        "$createdAt",
+		"unit":        bucket,
+		"startOfWeek": "monday",
+	}}
+
+	var (
+		result listensResult
+		err    error
+	)
+	groupings := []struct {
+		target *[]playsGroup
+		key    bson.M
+	}{
+		{&result.Totals, bson.M{}},
+		{&result.Timeline, bson.M{"bucket": bucketStart}},
+		{&result.Tracks, bson.M{"id": "$trackID"}},
+		{&result.Albums, bson.M{"id": "$albumID"}},
+		{&result.TrackTimeline, bson.M{"bucket": bucketStart, "id": "$trackID"}},
+		{&result.AlbumTimeline, bson.M{"bucket": bucketStart, "id": "$albumID"}},
+	}
+	for _, g := range groupings {
+		if *g.target, err = s.aggregatePlays(ctx, match, g.key); err != nil {
+			return nil, err
+		}
+	}
+	return &result, nil
+}
+
+// aggregatePlays groups matched listens by key.
+// Listens are grouped by listener first, so unique listeners
+// are counted without collecting their ids
+func (s *ArtistStatsService) aggregatePlays(
+	ctx context.Context, match, key bson.M,
+) ([]playsGroup, error) {
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$group": bson.M{
+			"_id":       bson.M{"key": key, "userID": "$userID"},
+			"plays":     bson.M{"$sum": 1},
+			"completed": bson.M{"$sum": bson.M{"$cond": bson.A{"$completed", 1, 0}}},
+		}},
+		// anonymous listens have no user and are not counted as unique listeners
+		{"$group": bson.M{
+			"_id":       "$_id.key",
+			"plays":     bson.M{"$sum": "$plays"},
+			"completed": bson.M{"$sum": "$completed"},
+			"listeners": bson.M{"$sum": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{"$_id.userID", ""}}, 1, 0,
+			}}},
+		}},
+	}
+
+	cur, err := s.listensCol.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	groups := []playsGroup{}
+	if err := cur.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// countPlaylistAdds returns number of public playlists containing each track.
+// Playlists don't store time of adding track, so the current number is returned.
+// Private playlists are not counted, they are not visible to artist
+func (s *ArtistStatsService) countPlaylistAdds(
+	ctx context.Context, trackIDs []string,
+) (map[string]int64, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"trackIDs": bson.M{"$in": trackIDs},
+			"isPublic": true,
+		}},
+		// track is counted once per playlist
+		{"$project": bson.M{
+			"trackIDs": bson.M{"$setIntersection": bson.A{"$trackIDs", trackIDs}},
+		}},
+		{"$unwind": "$trackIDs"},
+		{"$group": bson.M{
+			"_id":   "$trackIDs",
+			"count": bson.M{"$sum": 1},
+		}},
+	}
+
+	cur, err := s.playlistsCol.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var groups []struct {
+		TrackID string `bson:"_id"`
+		Count   int64  `bson:"count"`
+	}
+	if err := cur.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	adds := make(map[string]int64, len(groups))
+	for _, g := range groups {
+		adds[g.TrackID] = g.Count
+	}
+	return adds, nil
+}
+
+// aggregateListenerGenres returns top genres of all tracks
+// the artist listeners played in period
+func (s *ArtistStatsService) aggregateListenerGenres(
+	ctx context.Context, albumIDs []string, period bson.M,
+) ([]artistType.GenreShare, error) {
+	shares := []artistType.GenreShare{}
+
+	pipeline := []bson.M{
+		// artist listeners
+		{"$match": bson.M{
+			"albumID":   bson.M{"$in": albumIDs},
+			"createdAt": period,
+			"userID":    bson.M{"$gt": ""},
+		}},
+		{"$group": bson.M{"_id": "$userID"}},
+		// all listens of listener in period,
+		// $lookup followed by $unwind doesn't build arrays of listens
+		{"$lookup": bson.M{
+			"from": "listens",
+			"let":  bson.M{"userID": "$_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{
+					"$expr":     bson.M{"$eq": bson.A{"$userID", "$$userID"}},
+					"createdAt": period,
+				}},
+				bson.M{"$project": bson.M{"trackID": 1}},
+			},
+			"as": "listen",
+		}},
+		{"$unwind": "$listen"},
+		{"$lookup": bson.M{
+			"from":         "tracks",
+			"localField":   "listen.trackID",
+			"foreignField": "id",
+			"pipeline":     bson.A{bson.M{"$project": bson.M{"genre": 1}}},
+			"as":           "track",
+		}},
+		{"$unwind": "$track"},
+		{"$unwind": "$track.genre"},
+		{"$group": bson.M{
+			"_id":   bson.M{"$toLower": bson.M{"$trim": bson.M{"input": "$track.genre"}}},
+			"count": bson.M{"$sum": 1},
+		}},
+	}
+
+	cur, err := s.listensCol.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var groups []struct {
+		Genre string  `bson:"_id"`
+		Count float64 `bson:"count"`
+	}
+	if err := cur.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+
+	var total float64
+	for _, g := range groups {
+		total += g.Count
+	}
+	for _, g := range groups {
+		shares = append(shares, artistType.GenreShare{
+			Genre: g.Genre,
+			Share: round(g.Count / total),
+		})
+	}
+
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Share == shares[j].Share {
+			return shares[i].Genre < shares[j].Genre
+		}
+		return shares[i].Share > shares[j].Share
+	})
+	if len(shares) > topGenresCount {
+		shares = shares[:topGenresCount]
+	}
+	return shares, nil
+}
+
+// timeline fills every bucket of period, buckets without listens are zero
+func timeline(groups []playsGroup, params StatsParams) []artistType.StatsTimelinePoint {
+	plays := map[time.Time]playsGroup{}
+	for _, g := range groups {
+		plays[g.Key.Bucket.UTC()] = g
+	}
+
+	points := []artistType.StatsTimelinePoint{}
+	for start := bucketStart(params.From, params.Bucket); !start.After(params.To); start = nextBucket(start, params.Bucket) {
+		p := plays[start]
+		points = append(points, artistType.StatsTimelinePoint{
+			Start:           start.Format(dateLayout),
+			Plays:           p.Plays,
+			UniqueListeners: p.Listeners,
+		})
+	}
+	return points
+}
+
+// bucketStart truncates time to bucket like $dateTrunc does
+func bucketStart(t time.Time, bucket string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch bucket {
+	case artistType.BucketWeek:
+		// weeks start on monday
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case artistType.BucketMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+func nextBucket(t time.Time, bucket string) time.Time {
+	switch bucket {
+	case artistType.BucketWeek:
+		return t.AddDate(0, 0, 7)
+	case artistType.BucketMonth:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+func groupsByID(groups []playsGroup) map[string]playsGroup {
+	res := make(map[string]playsGroup, len(groups))
+	for _, g := range groups {
+		res[g.Key.ID] = g
+	}
+	return res
+}
+
+// splitByID returns timeline groups of every track or album
+func splitByID(groups []playsGroup) map[string][]playsGroup {
+	res := map[string][]playsGroup{}
+	for _, g := range groups {
+		res[g.Key.ID] = append(res[g.Key.ID], g)
+	}
+	return res
+}
+
+// rate returns part of completed plays
+func rate(completed, plays int64) float64 {
+	if plays == 0 {
+		return 0
+	}
+	return round(float64(completed) / float64(plays))
+}
+
+func round(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
@@ -5,6 +5,5 @@ type CreateRequest struct {
 }
 
 type UpdateRequest struct {
-	Name       *string `json:"name" validate:"omitempty,min=3,max=64"`
-	AvatarPath *string `json:"avatarPath" validate:"omitempty,min=3,max=255"`
+	Name *string `json:"name" validate:"omitempty,min=3,max=64"`
 }
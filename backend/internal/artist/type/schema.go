@@ -40,10 +40,29 @@ func EnsureIndexes(ctx context.Context, col *mongo.Collection) error {
 		Options: options.Index().SetUnique(true).SetName("id_unique"),
 	}
 
+	// text index for search by name
+	nameTextIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: "text"}},
+		Options: options.Index().SetName("name_text"),
+	}
+
 	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{
 		artistNameIndex,
 		userIDIndex,
 		idIndex,
+		nameTextIndex,
 	})
 	return err
 }
+
+// OwnArtistIDs returns ids of artists created by user,
+// anonymous user has no artists
+func OwnArtistIDs(ctx context.Context, col *mongo.Collection, userID string) ([]string, error) {
+	if userID == "" {
+		return nil, nil
+	}
+
+	ids := []string{}
+	err := col.Distinct(ctx, "id", bson.M{"userID": userID}).Decode(&ids)
+	return ids, err
+}
@@ -0,0 +1,63 @@
+package artistType
+
+// time buckets of statistics timeline
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+)
+
+// StatsResponse is an artist performance summary for period
+type StatsResponse struct {
+	ArtistID          string               `json:"artistID"`
+	Bucket            string               `json:"bucket"`
+	From              string               `json:"from"`
+	To                string               `json:"to"`
+	Totals            StatsTotals          `json:"totals"`
+	Timeline          []StatsTimelinePoint `json:"timeline"`
+	Tracks            []TrackStats         `json:"tracks"`
+	Albums            []AlbumStats         `json:"albums"`
+	TopListenerGenres []GenreShare         `json:"topListenerGenres"`
+	CachedAt          string               `json:"cachedAt"`
+}
+
+type StatsTotals struct {
+	Plays           int64   `json:"plays"`
+	CompletedPlays  int64   `json:"completedPlays"`
+	CompletionRate  float64 `json:"completionRate"`
+	UniqueListeners int64   `json:"uniqueListeners"`
+	PlaylistAdds    int64   `json:"playlistAdds"`
+}
+
+type StatsTimelinePoint struct {
+	Start           string `json:"start"`
+	Plays           int64  `json:"plays"`
+	UniqueListeners int64  `json:"uniqueListeners"`
+}
+
+type TrackStats struct {
+	TrackID         string               `json:"trackID"`
+	Title           string               `json:"title"`
+	AlbumID         string               `json:"albumID"`
+	Plays           int64                `json:"plays"`
+	CompletionRate  float64              `json:"completionRate"`
+	UniqueListeners int64                `json:"uniqueListeners"`
+	PlaylistAdds    int64                `json:"playlistAdds"`
+	Timeline        []StatsTimelinePoint `json:"timeline"`
+}
+
+type AlbumStats struct {
+	AlbumID         string               `json:"albumID"`
+	Title           string               `json:"title"`
+	Plays           int64                `json:"plays"`
+	CompletionRate  float64              `json:"completionRate"`
+	UniqueListeners int64                `json:"uniqueListeners"`
+	PlaylistAdds    int64                `json:"playlistAdds"`
+	Timeline        []StatsTimelinePoint `json:"timeline"`
+}
+
+// GenreShare is a part of listens of genre
+type GenreShare struct {
+	Genre string  `json:"genre"`
+	Share float64 `json:"share"`
+}
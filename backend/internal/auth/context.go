@@ -3,6 +3,7 @@ package auth
 type contextKey string
 
 const (
-	UserIDKey   contextKey = "userID"
-	UserRoleKey contextKey = "userRole"
+	UserIDKey    contextKey = "userID"
+	UserRoleKey  contextKey = "userRole"
+	SessionIDKey contextKey = "sessionID"
 )
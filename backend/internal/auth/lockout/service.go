@@ -0,0 +1,143 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"tracker-backend/internal/pkg/storage"
+)
+
+var ErrLocked = errors.New("too many failed login attempts")
+
+// LockedError is returned while login is locked
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s, retry in %s", ErrLocked.Error(), e.RetryAfter.Round(time.Second))
+}
+
+func (e *LockedError) Is(target error) bool {
+	return target == ErrLocked
+}
+
+// policy locks scope after free attempts are exhausted,
+// lock duration is doubled on every next failure
+type policy struct {
+	freeAttempts int64
+	baseLockout  time.Duration
+	maxLockout   time.Duration
+}
+
+var (
+	// failures of login from one client ip
+	clientPolicy = policy{freeAttempts: 5, baseLockout: time.Minute, maxLockout: time.Hour}
+	// failures of login from all ips, it throttles distributed brute force,
+	// so it allows more attempts and grows slower to limit lockout of owner
+	loginPolicy = policy{freeAttempts: 20, baseLockout: 15 * time.Second, maxLockout: 15 * time.Minute}
+)
+
+const (
+	// failures are forgotten after a day without failures
+	failuresTTL = 24 * time.Hour
+
+	failuresKeyPrefix = "auth:login_failures:"
+	lockKeyPrefix     = "auth:login_lock:"
+)
+
+// scope is a counter of failures with its policy
+type scope struct {
+	key    string
+	policy policy
+}
+
+// LoginLockoutService locks login after repeated failed attempts.
+// Login is locked for client ip, so others can't lock owner out for long,
+// and with slower backoff for all ips, so distributed attempts are throttled too
+type LoginLockoutService struct {
+	redis *storage.RedisClient
+}
+
+func NewLoginLockoutService(redis *storage.RedisClient) *LoginLockoutService {
+	return &LoginLockoutService{
+		redis: redis,
+	}
+}
+
+// Check returns LockedError if login is locked for client
+func (s *LoginLockoutService) Check(ctx context.Context, login, ip string) error {
+	var retryAfter time.Duration
+	for _, sc := range scopes(login, ip) {
+		ttl, err := s.redis.TTL(ctx, lockKeyPrefix+sc.key)
+		if err != nil {
+			return err
+		}
+		retryAfter = max(retryAfter, ttl)
+	}
+	if retryAfter > 0 {
+		return &LockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// Fail counts failed attempt and locks login if free attempts are exhausted
+func (s *LoginLockoutService) Fail(ctx context.Context, login, ip string) error {
+	var retryAfter time.Duration
+	for _, sc := range scopes(login, ip) {
+		lockout, err := s.fail(ctx, sc)
+		if err != nil {
+			return err
+		}
+		retryAfter = max(retryAfter, lockout)
+	}
+	if retryAfter > 0 {
+		return &LockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// fail counts failure of scope and returns lock duration if scope is locked
+func (s *LoginLockoutService) fail(ctx context.Context, sc scope) (time.Duration, error) {
+	failures, err := s.redis.IncrBy(ctx, failuresKeyPrefix+sc.key, 1, failuresTTL)
+	if err != nil {
+		return 0, err
+	}
+	if failures < sc.policy.freeAttempts {
+		return 0, nil
+	}
+
+	lockout := sc.policy.maxLockout
+	if shift := failures - sc.policy.freeAttempts; shift < 16 {
+		lockout = min(sc.policy.baseLockout<<shift, sc.policy.maxLockout)
+	}
+	if err := s.redis.SetString(ctx, lockKeyPrefix+sc.key, "1", lockout); err != nil {
+		return 0, err
+	}
+
+	slog.Info("login locked",
+		slog.String("function", "lockout.LoginLockoutService.fail"),
+		slog.String("scope", sc.key),
+		slog.Int64("failures", failures),
+		slog.Duration("lockout", lockout),
+	)
+	return lockout, nil
+}
+
+// Reset forgets failures after successful login
+func (s *LoginLockoutService) Reset(ctx context.Context, login, ip string) error {
+	keys := []string{}
+	for _, sc := range scopes(login, ip) {
+		keys = append(keys, failuresKeyPrefix+sc.key, lockKeyPrefix+sc.key)
+	}
+	return s.redis.Delete(ctx, keys...)
+}
+
+func scopes(login, ip string) []scope {
+	return []scope{
+		{key: "client:" + login + ":" + ip, policy: clientPolicy},
+		{key: "login:" + login, policy: loginPolicy},
+	}
+}
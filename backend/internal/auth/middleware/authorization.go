@@ -2,67 +2,132 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
-	"os"
+	"strings"
 	"tracker-backend/internal/auth"
-	"tracker-backend/internal/config"
 	"tracker-backend/internal/pkg/response"
 
 	"github.com/go-chi/render"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func Authorization(userProvider auth.UserProvider) auth.MiddlewareFunc {
+var (
+	errNoToken      = errors.New("authorization token not provided")
+	errInvalidToken = errors.New("invalid token")
+	errRevokedToken = errors.New("token revoked")
+)
+
+func Authorization(
+	userProvider auth.UserProvider, sessionChecker auth.SessionChecker, secret string,
+) auth.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// configure logger
 			logger := slog.With(slog.String("function", "middleware.Authorization"))
 
-			// get authorization header
-			tokenHeader := r.Header.Get("Authorization")
-			if tokenHeader == "" {
-				render.Status(r, http.StatusForbidden)
-				render.JSON(w, r, response.Error("access denied"))
-				return
-			}
-
-			tokenStr := tokenHeader[len("Bearer "):]
-			claims := jwt.MapClaims{}
-
-			// parse token
-			token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-				return []byte(os.Getenv(config.JWTSecretEnvName)), nil
-			})
-
-			// check token validness
-			if err != nil || !token.Valid {
+			user, sessionID, err := authenticate(r, userProvider, sessionChecker, secret)
+			if err != nil {
 				logger.Info("access denied", slog.String("error", err.Error()))
-				render.Status(r, http.StatusForbidden)
-				render.JSON(w, r, response.Error("invalid token"))
+				switch {
+				case errors.Is(err, errNoToken):
+					render.Status(r, http.StatusUnauthorized)
+					render.JSON(w, r, response.Error("authorization required"))
+				case errors.Is(err, errInvalidToken):
+					render.Status(r, http.StatusUnauthorized)
+					render.JSON(w, r, response.Error("invalid token"))
+				case errors.Is(err, errRevokedToken):
+					render.Status(r, http.StatusUnauthorized)
+					render.JSON(w, r, response.Error("token revoked"))
+				default:
+					render.Status(r, http.StatusUnauthorized)
+					render.JSON(w, r, response.Error("user not found"))
+				}
 				return
 			}
 
-			logger.Info("jwt payload decoded", "claims", claims)
+			next.ServeHTTP(w, r.WithContext(withUser(r.Context(), user, sessionID)))
+		})
+	}
+}
 
-			// get user by id
-			user, err := userProvider.GetAuthDTOByID(r.Context(), claims["id"].(string), int(claims["role"].(float64)))
+// OptionalAuthorization sets user id and role to context if valid token provided
+// requests without token (or with invalid one) are passed as anonymous
+func OptionalAuthorization(
+	userProvider auth.UserProvider, sessionChecker auth.SessionChecker, secret string,
+) auth.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, sessionID, err := authenticate(r, userProvider, sessionChecker, secret)
 			if err != nil {
-				logger.Info("failed to get user dto", slog.String("error", err.Error()))
-				render.Status(r, http.StatusNotFound)
-				render.JSON(w, r, response.Error("user not found"))
+				next.ServeHTTP(w, r)
 				return
 			}
 
-			// set user id and role to context
-			ctx := context.WithValue(
-				r.Context(), auth.UserIDKey, user.ID,
-			)
-			ctx = context.WithValue(
-				ctx, auth.UserRoleKey, user.Role,
-			)
-
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, r.WithContext(withUser(r.Context(), user, sessionID)))
 		})
 	}
 }
+
+// authenticate parses bearer token, checks it is not revoked and finds related user
+func authenticate(
+	r *http.Request, userProvider auth.UserProvider, sessionChecker auth.SessionChecker, secret string,
+) (*auth.AuthUser, string, error) {
+	// get authorization header
+	tokenHeader := r.Header.Get("Authorization")
+	if !strings.HasPrefix(tokenHeader, "Bearer ") {
+		return nil, "", errNoToken
+	}
+
+	tokenStr := tokenHeader[len("Bearer "):]
+	claims := jwt.MapClaims{}
+
+	// parse token
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	})
+
+	// check token validness
+	if err != nil || !token.Valid {
+		return nil, "", errInvalidToken
+	}
+
+	id, ok := claims["id"].(string)
+	if !ok {
+		return nil, "", errInvalidToken
+	}
+	role, ok := claims["role"].(float64)
+	if !ok {
+		return nil, "", errInvalidToken
+	}
+	// tokens issued before sessions have no session id
+	sessionID, ok := claims["sid"].(string)
+	if !ok || sessionID == "" {
+		return nil, "", errInvalidToken
+	}
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		return nil, "", errInvalidToken
+	}
+
+	// check denylist
+	if err := sessionChecker.CheckRevoked(r.Context(), id, sessionID, issuedAt.Time); err != nil {
+		return nil, "", errors.Join(errRevokedToken, err)
+	}
+
+	// get user by id
+	user, err := userProvider.GetAuthDTOByID(r.Context(), id, int(role))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return user, sessionID, nil
+}
+
+// withUser sets user id, role and session id to context
+func withUser(ctx context.Context, user *auth.AuthUser, sessionID string) context.Context {
+	ctx = context.WithValue(ctx, auth.UserIDKey, user.ID)
+	ctx = context.WithValue(ctx, auth.UserRoleKey, user.Role)
+	return context.WithValue(ctx, auth.SessionIDKey, sessionID)
+}
@@ -1 +1,55 @@
 package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"tracker-backend/internal/auth"
+	"tracker-backend/internal/pkg/response"
+
+	"github.com/go-chi/render"
+)
+
+// RequireRole allows access to users with role greater than or equal to minRole
+// must be used after Authorization middleware
+func RequireRole(minRole int) auth.MiddlewareFunc {
+	return requireRole(func(role int) bool {
+		return role >= minRole
+	})
+}
+
+// RequireAnyRole allows access to users with one of listed roles
+// must be used after Authorization middleware
+func RequireAnyRole(roles ...int) auth.MiddlewareFunc {
+	return requireRole(func(role int) bool {
+		return slices.Contains(roles, role)
+	})
+}
+
+func requireRole(allowed func(role int) bool) auth.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// get user role from context
+			role, ok := r.Context().Value(auth.UserRoleKey).(int)
+			if !ok {
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, response.Error("authorization required"))
+				return
+			}
+
+			// check role
+			if !allowed(role) {
+				slog.Info("access denied by role",
+					slog.String("function", "middleware.requireRole"),
+					slog.String("path", r.URL.Path),
+					slog.Int("role", role),
+				)
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, response.Error("insufficient role"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"tracker-backend/internal/auth"
+	"tracker-backend/internal/pkg/response"
+	"tracker-backend/internal/pkg/signedurl"
+
+	"github.com/go-chi/render"
+)
+
+// RequireSignedURL allows requests with valid url signature of resource.
+// Signed user is set to context of anonymous request, as media elements
+// can't send authorization header. Must be used after OptionalAuthorization
+func RequireSignedURL(secret string, resource func(r *http.Request) string) auth.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// configure logger
+			logger := slog.With(slog.String("function", "middleware.RequireSignedURL"))
+
+			signer, err := signedurl.Verify(secret, resource(r), r.URL.Query())
+			if err != nil {
+				logger.Info("access denied", slog.String("error", err.Error()))
+				switch {
+				case errors.Is(err, signedurl.ErrExpired):
+					render.Status(r, http.StatusForbidden)
+					render.JSON(w, r, response.Error("url expired"))
+				case errors.Is(err, signedurl.ErrMissingSignature):
+					render.Status(r, http.StatusUnauthorized)
+					render.JSON(w, r, response.Error("signed url required"))
+				default:
+					render.Status(r, http.StatusForbidden)
+					render.JSON(w, r, response.Error("invalid url signature"))
+				}
+				return
+			}
+
+			// url must be used by user it is signed for
+			ctx := r.Context()
+			userID, authorized := ctx.Value(auth.UserIDKey).(string)
+			if authorized && userID != signer.UserID {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, response.Error("url is signed for another user"))
+				return
+			}
+			if !authorized && signer.UserID != "" {
+				ctx = context.WithValue(ctx, auth.UserIDKey, signer.UserID)
+				ctx = context.WithValue(ctx, auth.UserRoleKey, signer.UserRole)
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
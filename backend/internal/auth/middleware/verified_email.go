@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"tracker-backend/internal/auth"
+	"tracker-backend/internal/pkg/response"
+
+	"github.com/go-chi/render"
+)
+
+// RequireVerifiedEmail blocks uploads of users with unverified email
+// if it is enabled by checker, must be used after Authorization middleware
+func RequireVerifiedEmail(checker auth.EmailVerificationChecker) auth.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := r.Context().Value(auth.UserIDKey).(string)
+			if !ok {
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, response.Error("authorization required"))
+				return
+			}
+
+			if err := checker.CheckEmailVerified(r.Context(), userID); err != nil {
+				if errors.Is(err, auth.ErrEmailNotVerified) {
+					render.Status(r, http.StatusForbidden)
+					render.JSON(w, r, response.Error(err.Error()))
+					return
+				}
+
+				slog.Warn("failed to check email verification",
+					slog.String("function", "middleware.RequireVerifiedEmail"),
+					slog.String("userID", userID),
+					slog.String("error", err.Error()),
+				)
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, response.Error("failed to check email verification"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
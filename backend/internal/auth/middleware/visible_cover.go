@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+	"tracker-backend/internal/auth"
+)
+
+// CoverVisibilityChecker checks that album of cover is visible to user
+type CoverVisibilityChecker interface {
+	IsCoverVisible(ctx context.Context, coverPath, userID string, userRole int) (bool, error)
+}
+
+// RequireVisibleCover serves covers of albums visible to user only,
+// covers of hidden albums and albums on moderation are not found
+// for other users. Must be used after OptionalAuthorization
+func RequireVisibleCover(checker CoverVisibilityChecker) auth.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			userID, _ := ctx.Value(auth.UserIDKey).(string)
+			userRole, _ := ctx.Value(auth.UserRoleKey).(int)
+
+			// url path "/public/covers/<name>" is stored as "public/covers/<name>"
+			coverPath := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+			visible, err := checker.IsCoverVisible(ctx, coverPath, userID, userRole)
+			if err != nil {
+				slog.Warn("failed to check cover visibility",
+					slog.String("function", "middleware.RequireVisibleCover"),
+					slog.String("coverPath", coverPath),
+					slog.String("error", err.Error()),
+				)
+				http.Error(w, "failed to check cover visibility", http.StatusInternalServerError)
+				return
+			}
+			if !visible {
+				http.NotFound(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
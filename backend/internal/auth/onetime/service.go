@@ -0,0 +1,107 @@
+package onetime
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+	"tracker-backend/internal/pkg/storage"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// token purposes, token of one purpose can't be used for another
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeResetPassword = "reset_password"
+)
+
+// user has one active token per purpose, redis keeps its hash
+const tokenKeyPrefix = "auth:onetime:"
+
+// TokenService issues signed one-time tokens sent to users by email.
+// Token is valid till it is used, expired or replaced by a newer one
+type TokenService struct {
+	redis  *storage.RedisClient
+	secret string
+}
+
+func NewTokenService(redis *storage.RedisClient, secret string) *TokenService {
+	return &TokenService{
+		redis:  redis,
+		secret: secret,
+	}
+}
+
+// Issue returns new token of user, previous token of purpose is revoked
+func (s *TokenService) Issue(
+	ctx context.Context, purpose, userID string, ttl time.Duration,
+) (string, error) {
+	nonce := make([]byte, 24)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString([]byte(userID)) + "." +
+		base64.RawURLEncoding.EncodeToString(nonce)
+	token := payload + "." + s.sign(purpose, payload)
+
+	if err := s.redis.SetString(ctx, tokenKey(purpose, userID), hashToken(token), ttl); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Consume checks token and revokes it, returns id of token user
+func (s *TokenService) Consume(
+	ctx context.Context, purpose, token string,
+) (string, error) {
+	// token format: <user id>.<nonce>.<signature>
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return "", ErrInvalidToken
+	}
+	payload, sig := token[:i], token[i+1:]
+
+	// forged tokens are rejected without redis lookup
+	if !hmac.Equal([]byte(sig), []byte(s.sign(purpose, payload))) {
+		return "", ErrInvalidToken
+	}
+
+	encodedID, _, _ := strings.Cut(payload, ".")
+	userID, err := base64.RawURLEncoding.DecodeString(encodedID)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+
+	consumed, err := s.redis.CompareAndDelete(ctx, tokenKey(purpose, string(userID)), hashToken(token))
+	if err != nil {
+		return "", errors.New("failed to check token")
+	}
+	if !consumed {
+		return "", ErrInvalidToken
+	}
+
+	return string(userID), nil
+}
+
+func (s *TokenService) sign(purpose, payload string) string {
+	mac := hmac.New(sha256.New, []byte(s.secret))
+	mac.Write([]byte(purpose + "\n" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func tokenKey(purpose, userID string) string {
+	return tokenKeyPrefix + purpose + ":" + userID
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
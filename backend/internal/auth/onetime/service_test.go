@@ -0,0 +1,190 @@
+package onetime
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"tracker-backend/internal/pkg/storage"
+
+	"github.com/google/uuid"
+)
+
+const testSecret = "test-secret"
+
+// testRedisAddrEnv is an address of redis used by tests,
+// tests using redis are skipped if it is not set
+const testRedisAddrEnv = "TEST_REDIS_ADDR"
+
+func newTestRedisClient(t *testing.T) *storage.RedisClient {
+	t.Helper()
+
+	addr := os.Getenv(testRedisAddrEnv)
+	if addr == "" {
+		t.Skipf("%s is not set", testRedisAddrEnv)
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatalf("invalid %s: %v", testRedisAddrEnv, err)
+	}
+
+	client, err := storage.NewRedisClient(context.Background(), host, port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// TestConsumeRejectsForged checks tokens rejected before redis lookup
+func TestConsumeRejectsForged(t *testing.T) {
+	// redis is not used for forged tokens
+	s := NewTokenService(nil, testSecret)
+	other := NewTokenService(nil, "other-secret")
+
+	userID := base64.RawURLEncoding.EncodeToString([]byte("user-1"))
+	payload := userID + ".nonce"
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "empty", token: ""},
+		{name: "no signature", token: payload},
+		{name: "forged signature", token: payload + ".forged"},
+		{name: "empty signature", token: payload + "."},
+		{name: "signed by other secret", token: payload + "." + other.sign(PurposeVerifyEmail, payload)},
+		{name: "signed for other purpose", token: payload + "." + s.sign(PurposeResetPassword, payload)},
+		{name: "changed user", token: base64.RawURLEncoding.EncodeToString([]byte("user-2")) + ".nonce." +
+			s.sign(PurposeVerifyEmail, payload)},
+		{name: "signed invalid user", token: "!!!.nonce." + s.sign(PurposeVerifyEmail, "!!!.nonce")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID, err := s.Consume(context.Background(), PurposeVerifyEmail, tt.token)
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("Consume error = %v, want %v", err, ErrInvalidToken)
+			}
+			if userID != "" {
+				t.Fatalf("Consume user = %q, want empty", userID)
+			}
+		})
+	}
+}
+
+func TestConsume(t *testing.T) {
+	redis := newTestRedisClient(t)
+	s := NewTokenService(redis, testSecret)
+
+	tests := []struct {
+		name string
+		// prepare issues tokens and returns consumed one
+		prepare func(t *testing.T, ctx context.Context, userID string) string
+		purpose string
+		err     error
+	}{
+		{
+			name: "valid token",
+			prepare: func(t *testing.T, ctx context.Context, userID string) string {
+				return mustIssue(t, s, PurposeVerifyEmail, userID, time.Hour)
+			},
+			purpose: PurposeVerifyEmail,
+		},
+		{
+			name: "used token",
+			prepare: func(t *testing.T, ctx context.Context, userID string) string {
+				token := mustIssue(t, s, PurposeVerifyEmail, userID, time.Hour)
+				if _, err := s.Consume(ctx, PurposeVerifyEmail, token); err != nil {
+					t.Fatalf("first Consume: %v", err)
+				}
+				return token
+			},
+			purpose: PurposeVerifyEmail,
+			err:     ErrInvalidToken,
+		},
+		{
+			name: "replaced token",
+			prepare: func(t *testing.T, ctx context.Context, userID string) string {
+				token := mustIssue(t, s, PurposeResetPassword, userID, time.Hour)
+				mustIssue(t, s, PurposeResetPassword, userID, time.Hour)
+				return token
+			},
+			purpose: PurposeResetPassword,
+			err:     ErrInvalidToken,
+		},
+		{
+			name: "token of other purpose is kept",
+			prepare: func(t *testing.T, ctx context.Context, userID string) string {
+				token := mustIssue(t, s, PurposeVerifyEmail, userID, time.Hour)
+				mustIssue(t, s, PurposeResetPassword, userID, time.Hour)
+				return token
+			},
+			purpose: PurposeVerifyEmail,
+		},
+		{
+			name: "used for other purpose",
+			prepare: func(t *testing.T, ctx context.Context, userID string) string {
+				return mustIssue(t, s, PurposeVerifyEmail, userID, time.Hour)
+			},
+			purpose: PurposeResetPassword,
+			err:     ErrInvalidToken,
+		},
+		{
+			name: "expired token",
+			prepare: func(t *testing.T, ctx context.Context, userID string) string {
+				token := mustIssue(t, s, PurposeVerifyEmail, userID, 50*time.Millisecond)
+				time.Sleep(100 * time.Millisecond)
+				return token
+			},
+			purpose: PurposeVerifyEmail,
+			err:     ErrInvalidToken,
+		},
+		{
+			name: "changed nonce",
+			prepare: func(t *testing.T, ctx context.Context, userID string) string {
+				token := mustIssue(t, s, PurposeVerifyEmail, userID, time.Hour)
+				parts := strings.Split(token, ".")
+				payload := parts[0] + ".changed"
+				return payload + "." + s.sign(PurposeVerifyEmail, payload)
+			},
+			purpose: PurposeVerifyEmail,
+			err:     ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			userID := uuid.NewString()
+			t.Cleanup(func() {
+				redis.Delete(ctx,
+					tokenKey(PurposeVerifyEmail, userID), tokenKey(PurposeResetPassword, userID),
+				)
+			})
+
+			token := tt.prepare(t, ctx, userID)
+			got, err := s.Consume(ctx, tt.purpose, token)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Consume error = %v, want %v", err, tt.err)
+			}
+			if tt.err == nil && got != userID {
+				t.Fatalf("Consume user = %q, want %q", got, userID)
+			}
+		})
+	}
+}
+
+func mustIssue(t *testing.T, s *TokenService, purpose, userID string, ttl time.Duration) string {
+	t.Helper()
+
+	token, err := s.Issue(context.Background(), purpose, userID, ttl)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	return token
+}
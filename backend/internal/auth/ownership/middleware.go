@@ -1 +1,93 @@
 package ownership
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"tracker-backend/internal/auth"
+	"tracker-backend/internal/pkg/response"
+	"tracker-backend/internal/pkg/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type contextKey string
+
+const resourceKey contextKey = "ownedResource"
+
+// NoBypass disables ownership bypass by role
+const NoBypass = 0
+
+var ErrNoResource = errors.New("resource is not loaded to context")
+
+// Require resolves {id} url param to resource owner and rejects non-owners.
+// Users with role greater than or equal to bypassRole skip the check (use NoBypass to disable).
+// Loaded resource is stored to context and can be read by ResourceFromContext.
+// Must be used after Authorization middleware
+func (s *OwnershipService) Require(resource Resource, bypassRole int) auth.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// configure logger
+			logger := slog.With(slog.String("function", "ownership.OwnershipService.Require"))
+
+			// get context keys
+			ctx := r.Context()
+			userID, ok := ctx.Value(auth.UserIDKey).(string)
+			if !ok {
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, response.Error("authorization required"))
+				return
+			}
+			userRole, _ := ctx.Value(auth.UserRoleKey).(int)
+
+			// get url param
+			id := chi.URLParam(r, "id")
+			if id == "" {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, response.Error("missing "+string(resource)+" id"))
+				return
+			}
+
+			// find resource and its owner
+			ownerID, raw, err := s.Resolve(ctx, resource, id)
+			if err != nil {
+				if errors.Is(err, service.ErrNotFound) {
+					render.Status(r, http.StatusNotFound)
+					render.JSON(w, r, response.Error(string(resource)+" not found"))
+					return
+				}
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, response.Error(err.Error()))
+				return
+			}
+
+			// check ownership
+			bypass := bypassRole != NoBypass && userRole >= bypassRole
+			if ownerID != userID && !bypass {
+				logger.Info("access denied",
+					slog.String("resource", string(resource)),
+					slog.String("id", id),
+					slog.String("userID", userID),
+				)
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, response.Error(service.ErrAccessDenied.Error()))
+				return
+			}
+
+			ctx = context.WithValue(ctx, resourceKey, raw)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// ResourceFromContext decodes resource loaded by Require middleware to target
+func ResourceFromContext(ctx context.Context, target any) error {
+	raw, ok := ctx.Value(resourceKey).(bson.Raw)
+	if !ok {
+		return ErrNoResource
+	}
+	return bson.Unmarshal(raw, target)
+}
@@ -4,179 +4,162 @@ import (
 	"context"
 	"errors"
 	"log/slog"
-	artistType "tracker-backend/internal/artist/type"
 	"tracker-backend/internal/pkg/service"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
+type Resource string
+
+const (
+	ResourceArtist   Resource = "artist"
+	ResourceAlbum    Resource = "album"
+	ResourceTrack    Resource = "track"
+	ResourcePlaylist Resource = "playlist"
+)
+
+// ownerIDField is a field added to resolved document
+// it contains id of user who owns resource
+const ownerIDField = "_ownerID"
+
 type OwnershipService struct {
-	artistsCol *mongo.Collection
-	albumsCol  *mongo.Collection
+	artistsCol   *mongo.Collection
+	albumsCol    *mongo.Collection
+	tracksCol    *mongo.Collection
+	playlistsCol *mongo.Collection
 }
 
-func NewOwnershipService(albumsCol *mongo.Collection, artistsCol *mongo.Collection) *OwnershipService {
+func NewOwnershipService(
+	albumsCol *mongo.Collection,
+	artistsCol *mongo.Collection,
+	tracksCol *mongo.Collection,
+	playlistsCol *mongo.Collection,
+) *OwnershipService {
 	return &OwnershipService{
-		albumsCol:  albumsCol,
-		artistsCol: artistsCol,
+		albumsCol:    albumsCol,
+		artistsCol:   artistsCol,
+		tracksCol:    tracksCol,
+		playlistsCol: playlistsCol,
 	}
 }
 
-// IsAlbumOwner checks album ownership
+// IsAlbumOwner returns true if user owns artist of album,
+// service.ErrNotFound is returned for missing album as in Require
 func (s *OwnershipService) IsAlbumOwner(ctx context.Context, userID, albumID string) (bool, error) {
-	pipeline := []bson.M{
-		{
-			"$match": bson.M{
-				"id":       albumID,
-				"artistID": bson.M{"$exists": true},
-			},
-		},
-		{
-			"$lookup": bson.M{
-				"from":         "artists",
-				"localField":   "artistID",
-				"foreignField": "id",
-				"as":           "artist",
-			},
-		},
-		{
-			"$unwind": "$artist",
-		},
-		{
-			"$match": bson.M{
-				"artist.userID": userID,
-			},
-		},
-		{
-			"$count": "matches",
-		},
-	}
-
-	logger := slog.With(slog.String("function", "ownership.OwnershipService.IsArtistOwner"))
-
-	// process aggregate by pipeline
-	cursor, err := s.albumsCol.Aggregate(ctx, pipeline)
+	ownerID, _, err := s.Resolve(ctx, ResourceAlbum, albumID)
 	if err != nil {
-		logger.Warn("failed to aggregate", slog.String("error", err.Error()))
 		return false, err
 	}
-	defer cursor.Close(ctx)
-
-	logger.Info("cursor length", "length", cursor.RemainingBatchLength())
-
-	var result struct {
-		Matches int `bson:"matches"`
-	}
-	if cursor.Next(ctx) {
-		if err := cursor.Decode(&result); err != nil {
-			logger.Warn("failed to decode", slog.String("error", err.Error()))
-			return false, err
-		}
-		logger.Info("decoded cursor", "result", result)
-		// check matches and return result
-		return result.Matches > 0, nil
-	}
-
-	return false, nil
+	return ownerID == userID, nil
 }
 
-// IsArtistOwner returns true if user is owns artist
+// IsArtistOwner returns true if user owns artist,
+// service.ErrNotFound is returned for missing artist as in Require
 func (s *OwnershipService) IsArtistOwner(
 	ctx context.Context, userID, artistID string,
 ) (bool, error) {
-	// configure logger
-	logger := slog.With(slog.String("function", "ownership.OwnershipService.IsArtistOwner"))
-
-	// get artist
-	var artistEntry artistType.Artist
-	err := s.artistsCol.FindOne(
-		ctx, bson.M{"userID": userID, "id": artistID},
-	).Decode(&artistEntry)
-
-	// process errors
+	ownerID, _, err := s.Resolve(ctx, ResourceArtist, artistID)
 	if err != nil {
-		logger.Warn("failed to findone", slog.String("error", err.Error()))
-		if err == mongo.ErrNoDocuments {
-			return false, service.ErrNotFound
-		}
-		return false, errors.New("failed to find artist")
+		return false, err
 	}
-
-	// check ownership
-	return artistEntry.UserID == userID, nil
+	return ownerID == userID, nil
 }
 
+// IsTrackOwner returns true if user owns artist of track's album,
+// service.ErrNotFound is returned for missing track as in Require
 func (s *OwnershipService) IsTrackOwner(
-	ctx context.Context, trackID, userID string,
+	ctx context.Context, userID, trackID string,
 ) (bool, error) {
-	pipeline := []bson.M{
-		{
-			"$match": bson.M{
-				"userID": userID,
-			},
-		},
-		{
-			"$lookup": bson.M{
-				"from":         "albums",
-				"localField":   "id",
-				"foreignField": "artistID",
-				"as":           "albums",
-			},
-		},
-		{
-			"$unwind": "$albums",
-		},
-		{
-			"$lookup": bson.M{
-				"from":         "tracks",
-				"localField":   "albums.id",
-				"foreignField": "album",
-				"as":           "tracks",
-			},
-		},
-		{
-			"$unwind": "$tracks",
-		},
-		{
-			"$match": bson.M{
-				"tracks.id": trackID,
-			},
-		},
-		{
-			"$project": bson.M{
-				"id": 0,
-				"isOwn": bson.M{
-					// здесь должен быть массив
-					"$eq": bson.A{"$userID", userID},
-				},
-			},
-		},
-		{
-			"$limit": 1,
-		},
+	ownerID, _, err := s.Resolve(ctx, ResourceTrack, trackID)
+	if err != nil {
+		return false, err
 	}
+	return ownerID == userID, nil
+}
 
-	logger := slog.With(slog.String("function", "ownership.OwnershipService.IsTrackOwner"))
+// Resolve finds resource by id and returns id of user who owns it
+// and raw resource document
+func (s *OwnershipService) Resolve(
+	ctx context.Context, resource Resource, id string,
+) (string, bson.Raw, error) {
+	// configure logger
+	logger := slog.With(slog.String("function", "ownership.OwnershipService.Resolve"))
+
+	var (
+		col      *mongo.Collection
+		pipeline []bson.M
+	)
+
+	match := bson.M{"$match": bson.M{"id": id}}
+	switch resource {
+	case ResourceArtist:
+		// artist -> user
+		col = s.artistsCol
+		pipeline = []bson.M{
+			match,
+			{"$addFields": bson.M{ownerIDField: "$userID"}},
+		}
+	case ResourcePlaylist:
+		// playlist -> user
+		col = s.playlistsCol
+		pipeline = []bson.M{
+			match,
+			{"$addFields": bson.M{ownerIDField: "$userID"}},
+		}
+	case ResourceAlbum:
+		// album -> artist -> user
+		col = s.albumsCol
+		pipeline = []bson.M{
+			match,
+			lookup("artists", "artistID", "_artist"),
+			{"$addFields": bson.M{ownerIDField: bson.M{"$first": "$_artist.userID"}}},
+			{"$project": bson.M{"_artist": 0}},
+		}
+	case ResourceTrack:
+		// track -> album -> artist -> user
+		col = s.tracksCol
+		pipeline = []bson.M{
+			match,
+			lookup("albums", "album", "_album"),
+			lookup("artists", "_album.artistID", "_artist"),
+			{"$addFields": bson.M{ownerIDField: bson.M{"$first": "$_artist.userID"}}},
+			{"$project": bson.M{"_album": 0, "_artist": 0}},
+		}
+	default:
+		return "", nil, errors.New("unknown resource")
+	}
 
-	cur, err := s.artistsCol.Aggregate(ctx, pipeline)
+	// process aggregate by pipeline
+	cur, err := col.Aggregate(ctx, append(pipeline, bson.M{"$limit": 1}))
 	if err != nil {
-		logger.Warn("failed to aggregate", slog.String("error", err.Error()))
-		return false, err
+		logger.Warn("failed to aggregate",
+			slog.String("resource", string(resource)),
+			slog.String("error", err.Error()),
+		)
+		return "", nil, errors.New("failed to check ownership")
 	}
 	defer cur.Close(ctx)
 
-	var res struct {
-		isOwn bool
+	if !cur.Next(ctx) {
+		return "", nil, service.ErrNotFound
 	}
 
-	if cur.Next(ctx) {
-		if err := cur.Decode(&res); err != nil {
-			logger.Warn("failed to decode", slog.String("error", err.Error()))
-			return false, err
-		}
-		return res.isOwn, nil
-	}
+	// copy document, cursor buffer is reused
+	raw := make(bson.Raw, len(cur.Current))
+	copy(raw, cur.Current)
+	ownerID, _ := raw.Lookup(ownerIDField).StringValueOK()
+
+	return ownerID, raw, nil
+}
 
-	return false, nil
+func lookup(from, localField, as string) bson.M {
+	return bson.M{
+		"$lookup": bson.M{
+			"from":         from,
+			"localField":   localField,
+			"foreignField": "id",
+			"as":           as,
+		},
+	}
 }
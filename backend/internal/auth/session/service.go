@@ -0,0 +1,240 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"tracker-backend/internal/auth"
+	authToken "tracker-backend/internal/pkg/authorization"
+	"tracker-backend/internal/pkg/storage"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrTokenRevoked        = errors.New("token revoked")
+)
+
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+
+	sessionKeyPrefix      = "auth:session:"         // session user
+	sessionRefreshPrefix  = "auth:session_refresh:" // hash of current refresh token
+	userSessionsKeyPrefix = "auth:user_sessions:"   // ids of user sessions
+	// denylist keys live while revoked access tokens are valid
+	deniedSessionKeyPrefix = "auth:deny:session:"
+	deniedUserKeyPrefix    = "auth:deny:user:" // revocation time of all user tokens
+)
+
+// TokenPair is issued on login and on every refresh
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time // access token expiration
+}
+
+// SessionService issues access and refresh tokens of user sessions.
+// Refresh token is rotated on every use, reuse of rotated token
+// revokes the whole session
+type SessionService struct {
+	redis  *storage.RedisClient
+	secret string
+}
+
+func NewSessionService(redis *storage.RedisClient, secret string) *SessionService {
+	return &SessionService{
+		redis:  redis,
+		secret: secret,
+	}
+}
+
+// Issue starts new session of user
+func (s *SessionService) Issue(ctx context.Context, user *auth.AuthUser) (*TokenPair, error) {
+	// configure logger
+	logger := slog.With(slog.String("function", "session.SessionService.Issue"))
+
+	sessionID := uuid.NewString()
+	refreshToken, err := newRefreshToken(sessionID)
+	if err != nil {
+		return nil, errors.New("failed to create refresh token")
+	}
+
+	if err := s.redis.SetJSON(ctx, sessionKeyPrefix+sessionID, user, RefreshTokenTTL); err != nil {
+		logger.Warn("failed to save session", slog.String("error", err.Error()))
+		return nil, errors.New("failed to save session")
+	}
+	if err := s.redis.SetString(ctx, sessionRefreshPrefix+sessionID, hashToken(refreshToken), RefreshTokenTTL); err != nil {
+		logger.Warn("failed to save refresh token", slog.String("error", err.Error()))
+		return nil, errors.New("failed to save session")
+	}
+	if err := s.redis.AddToSet(ctx, userSessionsKeyPrefix+user.ID, RefreshTokenTTL, sessionID); err != nil {
+		logger.Warn("failed to save user session", slog.String("error", err.Error()))
+		return nil, errors.New("failed to save session")
+	}
+
+	return s.tokenPair(user, sessionID, refreshToken)
+}
+
+// Refresh rotates refresh token and issues new access token
+func (s *SessionService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	// configure logger
+	logger := slog.With(slog.String("function", "session.SessionService.Refresh"))
+
+	sessionID, _, ok := strings.Cut(refreshToken, ".")
+	if !ok || uuid.Validate(sessionID) != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	var user auth.AuthUser
+	if err := s.redis.GetJSON(ctx, sessionKeyPrefix+sessionID, &user); err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	newToken, err := newRefreshToken(sessionID)
+	if err != nil {
+		return nil, errors.New("failed to create refresh token")
+	}
+
+	// only current token of session can be swapped
+	swapped, err := s.redis.CompareAndSwap(ctx,
+		sessionRefreshPrefix+sessionID, hashToken(refreshToken), hashToken(newToken), RefreshTokenTTL,
+	)
+	if err != nil {
+		logger.Warn("failed to rotate refresh token", slog.String("error", err.Error()))
+		return nil, errors.New("failed to refresh session")
+	}
+	if !swapped {
+		// rotated token is used again, it may be stolen
+		logger.Warn("refresh token reused",
+			slog.String("userID", user.ID),
+			slog.String("sessionID", sessionID),
+		)
+		if err := s.Revoke(ctx, user.ID, sessionID); err != nil {
+			logger.Warn("failed to revoke session", slog.String("error", err.Error()))
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if err := s.redis.SetJSON(ctx, sessionKeyPrefix+sessionID, user, RefreshTokenTTL); err != nil {
+		logger.Warn("failed to prolong session", slog.String("error", err.Error()))
+	}
+	if err := s.redis.AddToSet(ctx, userSessionsKeyPrefix+user.ID, RefreshTokenTTL, sessionID); err != nil {
+		logger.Warn("failed to prolong user sessions", slog.String("error", err.Error()))
+	}
+
+	return s.tokenPair(&user, sessionID, newToken)
+}
+
+// Revoke ends session, its access tokens are denied till expiration
+func (s *SessionService) Revoke(ctx context.Context, userID, sessionID string) error {
+	if err := s.redis.SetString(ctx, deniedSessionKeyPrefix+sessionID, "1", AccessTokenTTL); err != nil {
+		return err
+	}
+	if err := s.redis.Delete(ctx, sessionKeyPrefix+sessionID, sessionRefreshPrefix+sessionID); err != nil {
+		return err
+	}
+	return s.redis.RemoveFromSet(ctx, userSessionsKeyPrefix+userID, sessionID)
+}
+
+// RevokeUser ends all sessions of user,
+// access tokens issued before revocation are denied
+func (s *SessionService) RevokeUser(ctx context.Context, userID string) error {
+	// configure logger
+	logger := slog.With(slog.String("function", "session.SessionService.RevokeUser"))
+
+	revokedAt := strconv.FormatInt(time.Now().Unix(), 10)
+	if err := s.redis.SetString(ctx, deniedUserKeyPrefix+userID, revokedAt, AccessTokenTTL); err != nil {
+		logger.Warn("failed to deny user tokens", slog.String("error", err.Error()))
+		return errors.New("failed to revoke tokens")
+	}
+
+	sessionIDs, err := s.redis.GetSet(ctx, userSessionsKeyPrefix+userID)
+	if err != nil {
+		logger.Warn("failed to get user sessions", slog.String("error", err.Error()))
+		return errors.New("failed to revoke tokens")
+	}
+
+	keys := []string{userSessionsKeyPrefix + userID}
+	for _, sessionID := range sessionIDs {
+		keys = append(keys, sessionKeyPrefix+sessionID, sessionRefreshPrefix+sessionID)
+	}
+	if err := s.redis.Delete(ctx, keys...); err != nil {
+		logger.Warn("failed to delete user sessions", slog.String("error", err.Error()))
+		return errors.New("failed to revoke tokens")
+	}
+
+	logger.Info("user tokens revoked",
+		slog.String("userID", userID),
+		slog.Int("sessions", len(sessionIDs)),
+	)
+	return nil
+}
+
+// CheckRevoked returns ErrTokenRevoked if access token
+// of session issued at issuedAt is in denylist
+func (s *SessionService) CheckRevoked(
+	ctx context.Context, userID, sessionID string, issuedAt time.Time,
+) error {
+	values, err := s.redis.GetStrings(ctx,
+		deniedSessionKeyPrefix+sessionID, deniedUserKeyPrefix+userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if values[0] != "" {
+		return ErrTokenRevoked
+	}
+	if values[1] != "" {
+		revokedAt, err := strconv.ParseInt(values[1], 10, 64)
+		if err != nil {
+			return err
+		}
+		if issuedAt.Unix() < revokedAt {
+			return ErrTokenRevoked
+		}
+	}
+	return nil
+}
+
+func (s *SessionService) tokenPair(
+	user *auth.AuthUser, sessionID, refreshToken string,
+) (*TokenPair, error) {
+	expiresAt := time.Now().Add(AccessTokenTTL)
+	accessToken, err := authToken.CreateAuthToken(
+		user.Role, user.ID, user.Email, sessionID, s.secret, AccessTokenTTL,
+	)
+	if err != nil {
+		return nil, errors.New("failed to create access token")
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+// newRefreshToken returns opaque token "<session id>.<random>"
+func newRefreshToken(sessionID string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return sessionID + "." + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns hash of refresh token stored in redis
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,6 +1,12 @@
 package auth
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrEmailNotVerified = errors.New("email not verified")
 
 type AuthUser struct {
 	ID    string `bson:"id"`
@@ -17,3 +23,14 @@ const (
 type UserProvider interface {
 	GetAuthDTOByID(ctx context.Context, id string, role int) (*AuthUser, error)
 }
+
+// SessionChecker reports whether access token of session was revoked
+type SessionChecker interface {
+	CheckRevoked(ctx context.Context, userID, sessionID string, issuedAt time.Time) error
+}
+
+// EmailVerificationChecker returns ErrEmailNotVerified
+// if user must verify email before uploading files
+type EmailVerificationChecker interface {
+	CheckEmailVerified(ctx context.Context, userID string) error
+}
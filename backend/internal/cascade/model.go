@@ -0,0 +1,28 @@
+package cascade
+
+import "time"
+
+type DeletionResponse struct {
+	ID         string `json:"id"`
+	Resource   string `json:"resource"`
+	ResourceID string `json:"resourceID"`
+	Attempts   int    `json:"attempts"`
+	Report     Report `json:"report"`
+	StartedAt  string `json:"startedAt"`
+	FinishedAt string `json:"finishedAt,omitempty"`
+}
+
+func (d *Deletion) ToResponse() DeletionResponse {
+	res := DeletionResponse{
+		ID:         d.ID,
+		Resource:   d.Resource,
+		ResourceID: d.ResourceID,
+		Attempts:   d.Attempts,
+		Report:     d.Report,
+		StartedAt:  d.StartedAt.Format(time.RFC3339),
+	}
+	if d.FinishedAt != nil {
+		res.FinishedAt = d.FinishedAt.Format(time.RFC3339)
+	}
+	return res
+}
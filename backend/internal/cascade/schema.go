@@ -0,0 +1,73 @@
+package cascade
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// deleted resources
+const (
+	ResourceUser   = "user"
+	ResourceArtist = "artist"
+	ResourceAlbum  = "album"
+)
+
+const (
+	StatusPending = "pending"
+	StatusDone    = "done"
+)
+
+// Deletion is a cascade delete of resource with its dependents.
+// Pending deletion is resumed by the next attempt
+type Deletion struct {
+	ID         string     `bson:"id"`
+	Resource   string     `bson:"resource"`
+	ResourceID string     `bson:"resourceID"`
+	Status     string     `bson:"status"`
+	Attempts   int        `bson:"attempts"`
+	Report     Report     `bson:"report"`
+	StartedAt  time.Time  `bson:"startedAt"`
+	UpdatedAt  time.Time  `bson:"updatedAt"`
+	FinishedAt *time.Time `bson:"finishedAt,omitempty"`
+}
+
+// Report is a number of removed documents and files,
+// it's summed up over all attempts
+type Report struct {
+	Users             int64 `bson:"users" json:"users"`
+	Artists           int64 `bson:"artists" json:"artists"`
+	Albums            int64 `bson:"albums" json:"albums"`
+	Tracks            int64 `bson:"tracks" json:"tracks"`
+	Playlists         int64 `bson:"playlists" json:"playlists"`
+	PlaylistsUpdated  int64 `bson:"playlistsUpdated" json:"playlistsUpdated"` // playlists with removed track links
+	ModerationRecords int64 `bson:"moderationRecords" json:"moderationRecords"`
+	Listens           int64 `bson:"listens" json:"listens"`
+	Files             int64 `bson:"files" json:"files"`
+}
+
+func EnsureIndexes(ctx context.Context, col *mongo.Collection) error {
+	// only one pending deletion of resource
+	pendingIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "resource", Value: 1},
+			{Key: "resourceID", Value: 1},
+		},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"status": StatusPending}).
+			SetName("resource_pending_unique"),
+	}
+
+	// unique index by id string
+	idIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("id_unique"),
+	}
+
+	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{pendingIndex, idIndex})
+	return err
+}
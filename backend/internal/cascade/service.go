@@ -0,0 +1,413 @@
+package cascade
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+	"tracker-backend/internal/config"
+	uploadfile "tracker-backend/internal/pkg/file"
+	"tracker-backend/internal/pkg/service"
+	"tracker-backend/internal/pkg/storage"
+	"tracker-backend/internal/track"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type GenreModelRemover interface {
+	DeleteModel(ctx context.Context, userID string) error
+}
+
+type CascadeService struct {
+	usersCol          *mongo.Collection
+	artistsCol        *mongo.Collection
+	albumsCol         *mongo.Collection
+	tracksCol         *mongo.Collection
+	playlistsCol      *mongo.Collection
+	moderationsCol    *mongo.Collection
+	listensCol        *mongo.Collection
+	deletionsCol      *mongo.Collection
+	genreModelRemover GenreModelRemover
+	blobStore         storage.BlobStore
+}
+
+func NewCascadeService(
+	usersCol, artistsCol, albumsCol, tracksCol *mongo.Collection,
+	playlistsCol, moderationsCol, listensCol, deletionsCol *mongo.Collection,
+	genreModelRemover GenreModelRemover,
+	blobStore storage.BlobStore,
+) *CascadeService {
+	return &CascadeService{
+		usersCol:          usersCol,
+		artistsCol:        artistsCol,
+		albumsCol:         albumsCol,
+		tracksCol:         tracksCol,
+		playlistsCol:      playlistsCol,
+		moderationsCol:    moderationsCol,
+		listensCol:        listensCol,
+		deletionsCol:      deletionsCol,
+		genreModelRemover: genreModelRemover,
+		blobStore:         blobStore,
+	}
+}
+
+// deleteFunc removes resource with its dependents.
+// Dependents and files are removed before documents referencing them,
+// so failed deletion can be repeated from the beginning
+type deleteFunc func(ctx context.Context, d *Deletion, id string) error
+
+// DeleteUser removes user with artists, playlists and listens
+func (s *CascadeService) DeleteUser(ctx context.Context, userID string) (*Deletion, error) {
+	return s.run(ctx, ResourceUser, userID)
+}
+
+// DeleteArtist removes artist with albums and avatar
+func (s *CascadeService) DeleteArtist(ctx context.Context, artistID string) (*Deletion, error) {
+	return s.run(ctx, ResourceArtist, artistID)
+}
+
+// DeleteAlbum removes album with tracks and cover
+func (s *CascadeService) DeleteAlbum(ctx context.Context, albumID string) (*Deletion, error) {
+	return s.run(ctx, ResourceAlbum, albumID)
+}
+
+// ResumePending repeats deletions failed midway
+func (s *CascadeService) ResumePending(ctx context.Context) error {
+	// configure logger
+	logger := slog.With(slog.String("function", "cascade.CascadeService.ResumePending"))
+
+	var pending []Deletion
+	cur, err := s.deletionsCol.Find(ctx, bson.M{"status": StatusPending})
+	if err != nil || cur.All(ctx, &pending) != nil {
+		return errors.New("failed to get pending deletions")
+	}
+
+	for _, d := range pending {
+		if _, err := s.run(ctx, d.Resource, d.ResourceID); err != nil && !errors.Is(err, service.ErrNotFound) {
+			logger.Warn("failed to resume deletion",
+				slog.String("id", d.ID),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+	return nil
+}
+
+// run starts or resumes deletion of resource
+func (s *CascadeService) run(ctx context.Context, resource, id string) (*Deletion, error) {
+	// configure logger
+	logger := slog.With(slog.String("function", "cascade.CascadeService.run"))
+
+	var (
+		col   *mongo.Collection
+		apply deleteFunc
+	)
+	switch resource {
+	case ResourceUser:
+		col, apply = s.usersCol, s.deleteUser
+	case ResourceArtist:
+		col, apply = s.artistsCol, s.deleteArtist
+	case ResourceAlbum:
+		col, apply = s.albumsCol, s.deleteAlbum
+	default:
+		return nil, errors.New("unknown resource")
+	}
+
+	// resource deleted earlier can be resumed only by pending deletion
+	count, err := col.CountDocuments(ctx, bson.M{"id": id})
+	if err != nil {
+		return nil, errors.New("failed to check resource existence")
+	}
+	if count < 1 {
+		pending, err := s.deletionsCol.CountDocuments(ctx, bson.M{
+			"resource": resource, "resourceID": id, "status": StatusPending,
+		})
+		if err != nil {
+			return nil, errors.New("failed to check pending deletion")
+		}
+		if pending < 1 {
+			return nil, service.ErrNotFound
+		}
+	}
+
+	d, err := s.begin(ctx, resource, id)
+	if err != nil {
+		logger.Warn("failed to begin deletion", slog.String("error", err.Error()))
+		return nil, errors.New("failed to begin deletion")
+	}
+
+	// deletion stays pending on failure
+	if err := apply(ctx, d, id); err != nil {
+		logger.Warn("deletion failed",
+			slog.Group("info",
+				slog.String("id", d.ID),
+				slog.String("resource", resource),
+				slog.String("resourceID", id),
+				slog.Int("attempts", d.Attempts),
+			),
+			slog.String("error", err.Error()),
+		)
+		return nil, errors.New("failed to delete " + resource + ", try again")
+	}
+
+	d, err = s.finish(ctx, d)
+	if err != nil {
+		return nil, errors.New("failed to finish deletion")
+	}
+
+	logger.Info("resource deleted",
+		slog.Group("info",
+			slog.String("id", d.ID),
+			slog.String("resource", resource),
+			slog.String("resourceID", id),
+			slog.Int("attempts", d.Attempts),
+		),
+	)
+
+	return d, nil
+}
+
+// begin creates pending deletion or returns existing one
+func (s *CascadeService) begin(ctx context.Context, resource, id string) (*Deletion, error) {
+	now := time.Now()
+	var d Deletion
+	err := s.deletionsCol.FindOneAndUpdate(ctx,
+		bson.M{"resource": resource, "resourceID": id, "status": StatusPending},
+		bson.M{
+			"$setOnInsert": bson.M{
+				"id":        uuid.NewString(),
+				"report":    Report{},
+				"startedAt": now,
+			},
+			"$set": bson.M{"updatedAt": now},
+			"$inc": bson.M{"attempts": 1},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&d)
+	return &d, err
+}
+
+// finish marks deletion done
+func (s *CascadeService) finish(ctx context.Context, d *Deletion) (*Deletion, error) {
+	now := time.Now()
+	var finished Deletion
+	err := s.deletionsCol.FindOneAndUpdate(ctx,
+		bson.M{"id": d.ID},
+		bson.M{"$set": bson.M{
+			"status":     StatusDone,
+			"updatedAt":  now,
+			"finishedAt": now,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&finished)
+	return &finished, err
+}
+
+// count adds number of removed items to deletion report
+func (s *CascadeService) count(ctx context.Context, d *Deletion, field string, n int64) error {
+	if n == 0 {
+		return nil
+	}
+	_, err := s.deletionsCol.UpdateOne(ctx,
+		bson.M{"id": d.ID},
+		bson.M{"$inc": bson.M{"report." + field: n}},
+	)
+	return err
+}
+
+func (s *CascadeService) deleteUser(ctx context.Context, d *Deletion, userID string) error {
+	// remove artists of user
+	artistIDs := []string{}
+	if err := s.artistsCol.Distinct(ctx, "id", bson.M{"userID": userID}).Decode(&artistIDs); err != nil {
+		return err
+	}
+	for _, artistID := range artistIDs {
+		if err := s.deleteArtist(ctx, d, artistID); err != nil {
+			return err
+		}
+	}
+
+	// remove playlists, listens and genre model
+	res, err := s.playlistsCol.DeleteMany(ctx, bson.M{"userID": userID})
+	if err != nil {
+		return err
+	}
+	if err := s.count(ctx, d, "playlists", res.DeletedCount); err != nil {
+		return err
+	}
+
+	res, err = s.listensCol.DeleteMany(ctx, bson.M{"userID": userID})
+	if err != nil {
+		return err
+	}
+	if err := s.count(ctx, d, "listens", res.DeletedCount); err != nil {
+		return err
+	}
+
+	if err := s.genreModelRemover.DeleteModel(ctx, userID); err != nil {
+		return err
+	}
+
+	res, err = s.usersCol.DeleteOne(ctx, bson.M{"id": userID})
+	if err != nil {
+		return err
+	}
+	return s.count(ctx, d, "users", res.DeletedCount)
+}
+
+func (s *CascadeService) deleteArtist(ctx context.Context, d *Deletion, artistID string) error {
+	var artist struct {
+		AvatarPath string `bson:"avatarPath"`
+	}
+	err := s.artistsCol.FindOne(ctx, bson.M{"id": artistID}).Decode(&artist)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// removed by previous attempt
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// remove albums of artist
+	albumIDs := []string{}
+	if err := s.albumsCol.Distinct(ctx, "id", bson.M{"artistID": artistID}).Decode(&albumIDs); err != nil {
+		return err
+	}
+	for _, albumID := range albumIDs {
+		if err := s.deleteAlbum(ctx, d, albumID); err != nil {
+			return err
+		}
+	}
+
+	if key, ok := uploadfile.UploadedKey(artist.AvatarPath, config.AvatarsDir); ok {
+		if err := s.removeFile(ctx, d, key); err != nil {
+			return err
+		}
+	}
+
+	res, err := s.artistsCol.DeleteOne(ctx, bson.M{"id": artistID})
+	if err != nil {
+		return err
+	}
+	return s.count(ctx, d, "artists", res.DeletedCount)
+}
+
+func (s *CascadeService) deleteAlbum(ctx context.Context, d *Deletion, albumID string) error {
+	var album struct {
+		CoverPath string `bson:"coverPath"`
+	}
+	err := s.albumsCol.FindOne(ctx, bson.M{"id": albumID}).Decode(&album)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// removed by previous attempt
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.deleteTracks(ctx, d, albumID); err != nil {
+		return err
+	}
+
+	if key, ok := uploadfile.UploadedKey(album.CoverPath, config.CoversDir); ok {
+		if err := s.removeFile(ctx, d, key); err != nil {
+			return err
+		}
+	}
+
+	res, err := s.moderationsCol.DeleteMany(ctx, bson.M{"albumID": albumID})
+	if err != nil {
+		return err
+	}
+	if err := s.count(ctx, d, "moderationRecords", res.DeletedCount); err != nil {
+		return err
+	}
+
+	res, err = s.albumsCol.DeleteOne(ctx, bson.M{"id": albumID})
+	if err != nil {
+		return err
+	}
+	return s.count(ctx, d, "albums", res.DeletedCount)
+}
+
+// deleteTracks removes album tracks, their audio and hls files
+// and links to them from all playlists
+func (s *CascadeService) deleteTracks(ctx context.Context, d *Deletion, albumID string) error {
+	var tracks []track.Track
+	cur, err := s.tracksCol.Find(ctx,
+		bson.M{"album": albumID},
+		options.Find().SetProjection(bson.M{"id": 1, "audioFile": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	if err := cur.All(ctx, &tracks); err != nil {
+		return err
+	}
+	if len(tracks) == 0 {
+		return nil
+	}
+
+	trackIDs := make([]string, len(tracks))
+	for i, t := range tracks {
+		trackIDs[i] = t.ID
+	}
+
+	// remove links from playlists
+	res, err := s.playlistsCol.UpdateMany(ctx,
+		bson.M{"trackIDs": bson.M{"$in": trackIDs}},
+		bson.M{
+			"$pull": bson.M{"trackIDs": bson.M{"$in": trackIDs}},
+			"$set":  bson.M{"updatedAt": time.Now()},
+			"$inc":  bson.M{"version": 1},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if err := s.count(ctx, d, "playlistsUpdated", res.ModifiedCount); err != nil {
+		return err
+	}
+
+	// remove audio and hls files
+	for _, t := range tracks {
+		if t.AudioFile == "" {
+			continue
+		}
+		if err := s.removeFile(ctx, d, t.AudioKey()); err != nil {
+			return err
+		}
+		hlsFiles, err := s.blobStore.List(ctx, t.HLSDir()+"/")
+		if err != nil {
+			return err
+		}
+		for _, f := range hlsFiles {
+			if err := s.removeFile(ctx, d, f.Key); err != nil {
+				return err
+			}
+		}
+	}
+
+	deleted, err := s.tracksCol.DeleteMany(ctx, bson.M{"id": bson.M{"$in": trackIDs}})
+	if err != nil {
+		return err
+	}
+	return s.count(ctx, d, "tracks", deleted.DeletedCount)
+}
+
+// removeFile removes blob if it exists
+func (s *CascadeService) removeFile(ctx context.Context, d *Deletion, key string) error {
+	if _, err := s.blobStore.Stat(ctx, key); err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) || errors.Is(err, storage.ErrInvalidBlobKey) {
+			return nil
+		}
+		return err
+	}
+	if err := s.blobStore.Delete(ctx, key); err != nil {
+		return err
+	}
+	return s.count(ctx, d, "files", 1)
+}
@@ -1,18 +1,86 @@
 package config
 
-const (
-	LoadEnvironmentEnvName string = "ENV"
-	PortEnvName            string = "PORT"
-	MongoURLEnvName        string = "MONGO_URL"
-	MongoDBNameEnvName     string = "MONGO_DB_NAME"
-	RedisHostEnvName       string = "REDIS_HOST"
-	RedisPortEnvName       string = "REDIS_PORT"
-	JWTSecretEnvName       string = "SECRET"
-	PublicDirPathEnvName   string = "PUBLIC_PATH"
-)
+import "time"
+
+// Config is the application configuration.
+// Values are read from optional yaml file, then overridden by env,
+// env names are listed in env tags
+type Config struct {
+	Env  string `yaml:"env" env:"ENV"` // local or prod
+	Port int    `yaml:"port" env:"PORT"`
+	// secret of access tokens, signed urls and one-time tokens
+	Secret string `yaml:"secret" env:"SECRET"`
+	// dir of local blob store
+	PublicPath string `yaml:"publicPath" env:"PUBLIC_PATH"`
+	// frontend url used in email links
+	AppURL string `yaml:"appURL" env:"APP_URL"`
+	// block uploads of users with unverified email
+	RequireVerifiedEmail bool `yaml:"requireVerifiedEmail" env:"REQUIRE_VERIFIED_EMAIL"`
+	// CIDRs or ips of proxies allowed to set client ip by
+	// X-Forwarded-For and X-Real-IP headers, env value is comma separated
+	TrustedProxies []string `yaml:"trustedProxies" env:"TRUSTED_PROXIES"`
+
+	Mongo     MongoConfig     `yaml:"mongo"`
+	Redis     RedisConfig     `yaml:"redis"`
+	BlobStore BlobStoreConfig `yaml:"blobStore"`
+	MediaGC   MediaGCConfig   `yaml:"mediaGC"`
+	HLS       HLSConfig       `yaml:"hls"`
+	Mail      MailConfig      `yaml:"mail"`
+}
+
+type MongoConfig struct {
+	URL    string `yaml:"url" env:"MONGO_URL"`
+	DBName string `yaml:"dbName" env:"MONGO_DB_NAME"`
+}
+
+type RedisConfig struct {
+	Host string `yaml:"host" env:"REDIS_HOST"`
+	Port int    `yaml:"port" env:"REDIS_PORT"`
+}
+
+type BlobStoreConfig struct {
+	Type string   `yaml:"type" env:"BLOB_STORE"` // local or s3
+	S3   S3Config `yaml:"s3"`
+}
+
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint" env:"S3_ENDPOINT"`
+	AccessKey string `yaml:"accessKey" env:"S3_ACCESS_KEY"`
+	SecretKey string `yaml:"secretKey" env:"S3_SECRET_KEY"`
+	Bucket    string `yaml:"bucket" env:"S3_BUCKET"`
+	Region    string `yaml:"region" env:"S3_REGION"`
+	UseSSL    bool   `yaml:"useSSL" env:"S3_USE_SSL"`
+}
+
+type MediaGCConfig struct {
+	Mode  string        `yaml:"mode" env:"MEDIA_GC_MODE"` // delete, quarantine or dry-run
+	Grace time.Duration `yaml:"grace" env:"MEDIA_GC_GRACE"`
+}
+
+type HLSConfig struct {
+	FFmpegPath string `yaml:"ffmpegPath" env:"FFMPEG_PATH"`
+}
+
+type MailConfig struct {
+	Mailer string     `yaml:"mailer" env:"MAILER"` // smtp, file or log
+	From   string     `yaml:"from" env:"MAIL_FROM"`
+	Dir    string     `yaml:"dir" env:"MAIL_DIR"` // dir of file mailer
+	SMTP   SMTPConfig `yaml:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host" env:"SMTP_HOST"`
+	Port     int    `yaml:"port" env:"SMTP_PORT"`
+	Username string `yaml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD"`
+}
 
 const (
 	AudioDir   string = "audio"
 	AvatarsDir string = "avatars"
 	CoversDir  string = "covers"
+	// hls playlists and segments of audio files
+	HLSDir string = "hls"
+	// unreferenced files are moved here by media gc
+	QuarantineDir string = "quarantine"
 )
@@ -1,14 +1,154 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/joho/godotenv"
+	"go.yaml.in/yaml/v3"
 )
 
-// load dotenv
-// if could not load - panic
-func MustLoadConfig() {
-	err := godotenv.Load()
+// FileEnvName is env of optional yaml config file path
+const FileEnvName = "CONFIG_FILE"
+
+// defaults of optional values
+func defaults() *Config {
+	return &Config{
+		Env:        "local",
+		Port:       8000,
+		PublicPath: "public",
+		Redis:      RedisConfig{Port: 6379},
+		BlobStore:  BlobStoreConfig{Type: "local", S3: S3Config{Region: "us-east-1"}},
+		MediaGC:    MediaGCConfig{Mode: "quarantine", Grace: 24 * time.Hour},
+		HLS:        HLSConfig{FFmpegPath: "ffmpeg"},
+		Mail:       MailConfig{Mailer: "log", Dir: "tmp/mail", SMTP: SMTPConfig{Port: 25}},
+	}
+}
+
+// Load reads config from yaml file and env, then validates it.
+// .env is optional, variables set in environment take precedence over it
+func Load() (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to load .env: %w", err)
+	}
+
+	cfg := defaults()
+
+	if path := os.Getenv(FileEnvName); path != "" {
+		if err := loadFile(cfg, path); err != nil {
+			return nil, fmt.Errorf("failed to load config file: %w", err)
+		}
+	}
+
+	if err := loadEnv(reflect.ValueOf(cfg).Elem()); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// MustLoadConfig loads config, if could not load - panic
+func MustLoadConfig() *Config {
+	cfg, err := Load()
 	if err != nil {
-		panic("failed to load .env")
+		panic(err.Error())
+	}
+	return cfg
+}
+
+// loadFile decodes yaml file over defaults, unknown keys are rejected
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// loadEnv sets fields with env tag from set env variables,
+// nested structs are walked recursively
+func loadEnv(v reflect.Value) error {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := v.Field(i)
+
+		if field.Kind() == reflect.Struct {
+			if err := loadEnv(field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := t.Field(i).Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		// empty values are treated as unset
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+
+		if err := setField(field, value); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func setField(field reflect.Value, value string) error {
+	switch {
+	case field.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+	case field.Kind() == reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+		// comma separated list
+		values := []string{}
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		field.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
 	}
+	return nil
 }
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"tracker-backend/internal/pkg/clientip"
+)
+
+// minimal secret length in prod
+const minProdSecretLength = 32
+
+// Validate checks required values and ranges,
+// all found problems are returned at once
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(slices.Contains([]string{"local", "prod"}, c.Env), "ENV must be local or prod")
+	check(validPort(c.Port), "PORT must be in range 1-65535")
+	check(c.Secret != "", "SECRET is required")
+	if c.Env == "prod" {
+		check(len(c.Secret) >= minProdSecretLength,
+			"SECRET must be at least %d characters in prod", minProdSecretLength)
+	}
+	if c.AppURL != "" {
+		u, err := url.Parse(c.AppURL)
+		check(err == nil && u.Scheme != "" && u.Host != "", "APP_URL must be absolute url")
+	}
+	if _, err := clientip.ParseProxies(c.TrustedProxies); err != nil {
+		errs = append(errs, fmt.Errorf("TRUSTED_PROXIES: %w", err))
+	}
+
+	check(c.Mongo.URL != "", "MONGO_URL is required")
+	check(c.Mongo.DBName != "", "MONGO_DB_NAME is required")
+	check(c.Redis.Host != "", "REDIS_HOST is required")
+	check(validPort(c.Redis.Port), "REDIS_PORT must be in range 1-65535")
+
+	switch c.BlobStore.Type {
+	case "local":
+		if err := checkWritableDir(c.PublicPath); err != nil {
+			errs = append(errs, fmt.Errorf("PUBLIC_PATH: %w", err))
+		}
+	case "s3":
+		check(c.BlobStore.S3.Endpoint != "", "S3_ENDPOINT is required")
+		check(c.BlobStore.S3.AccessKey != "", "S3_ACCESS_KEY is required")
+		check(c.BlobStore.S3.SecretKey != "", "S3_SECRET_KEY is required")
+		check(c.BlobStore.S3.Bucket != "", "S3_BUCKET is required")
+	default:
+		check(false, "BLOB_STORE must be local or s3")
+	}
+
+	check(slices.Contains([]string{"delete", "quarantine", "dry-run"}, c.MediaGC.Mode),
+		"MEDIA_GC_MODE must be delete, quarantine or dry-run")
+	check(c.MediaGC.Grace > 0, "MEDIA_GC_GRACE must be positive")
+	check(c.HLS.FFmpegPath != "", "FFMPEG_PATH is required")
+
+	switch c.Mail.Mailer {
+	case "log":
+	case "file":
+		check(c.Mail.Dir != "", "MAIL_DIR is required")
+	case "smtp":
+		check(c.Mail.SMTP.Host != "", "SMTP_HOST is required")
+		check(validPort(c.Mail.SMTP.Port), "SMTP_PORT must be in range 1-65535")
+		check(c.Mail.From != "", "MAIL_FROM is required")
+	default:
+		check(false, "MAILER must be smtp, file or log")
+	}
+
+	return errors.Join(errs...)
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+// checkWritableDir checks dir exists and files can be created in it
+func checkWritableDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a dir", dir)
+	}
+
+	file, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", dir, err)
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
@@ -1 +1,114 @@
 package genre
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	genreType "tracker-backend/internal/genre/type"
+	"tracker-backend/internal/pkg/service"
+	"tracker-backend/internal/pkg/storage"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// weights of user actions in genre model
+const (
+	WeightListen   = 1.0
+	WeightPlaylist = 2.0
+)
+
+const genreModelKeyPrefix = "genre_model:"
+
+type GenreModelService struct {
+	tracksCol *mongo.Collection
+	redis     *storage.RedisClient
+}
+
+func NewGenreModelService(
+	tracksCol *mongo.Collection, redis *storage.RedisClient,
+) *GenreModelService {
+	return &GenreModelService{
+		tracksCol: tracksCol,
+		redis:     redis,
+	}
+}
+
+// AddTrack adds weight of track genres to user genre model.
+// Weight is split between track genres
+func (s *GenreModelService) AddTrack(
+	ctx context.Context, userID, trackID string, weight float64,
+) error {
+	// configure logger
+	logger := slog.With(slog.String("function", "genre.GenreModelService.AddTrack"))
+
+	// get track genres
+	var track struct {
+		Genre []string `bson:"genre"`
+	}
+	err := s.tracksCol.FindOne(ctx,
+		bson.M{"id": trackID},
+		options.FindOne().SetProjection(bson.M{"genre": 1}),
+	).Decode(&track)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return service.ErrNotFound
+		}
+		return errors.New("failed to get track")
+	}
+
+	genres := NormalizeGenres(track.Genre)
+	if len(genres) == 0 {
+		return nil
+	}
+
+	// increment weights
+	key := genreModelKey(userID)
+	for _, g := range genres {
+		if err := s.redis.IncrHashFloat(ctx, key, g, weight/float64(len(genres))); err != nil {
+			logger.Warn("failed to update genre model", slog.String("error", err.Error()))
+			return errors.New("failed to update genre model")
+		}
+	}
+
+	return nil
+}
+
+// GetModel returns user genre model
+// user without history has empty model
+func (s *GenreModelService) GetModel(
+	ctx context.Context, userID string,
+) (genreType.GenreModel, error) {
+	model, err := s.redis.GetHashFloat(ctx, genreModelKey(userID))
+	if err != nil {
+		return nil, errors.New("failed to get genre model")
+	}
+	return genreType.GenreModel(model), nil
+}
+
+// DeleteModel removes user genre model
+func (s *GenreModelService) DeleteModel(ctx context.Context, userID string) error {
+	if err := s.redis.Delete(ctx, genreModelKey(userID)); err != nil {
+		return errors.New("failed to delete genre model")
+	}
+	return nil
+}
+
+// NormalizeGenres returns unique allowed genres in lower case
+func NormalizeGenres(genres []string) []string {
+	res := make([]string, 0, len(genres))
+	for _, g := range genres {
+		g = strings.ToLower(strings.TrimSpace(g))
+		if slices.Contains(genreType.AllowedGenres, g) && !slices.Contains(res, g) {
+			res = append(res, g)
+		}
+	}
+	return res
+}
+
+func genreModelKey(userID string) string {
+	return genreModelKeyPrefix + userID
+}
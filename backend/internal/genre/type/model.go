@@ -9,7 +9,25 @@ import (
 	"github.com/go-playground/validator/v10"
 )
 
-type GenreModel map[string]string
+// GenreModel contains user preference weight of each genre
+type GenreModel map[string]float64
+
+// Normalized returns model with weights summing to 1
+func (m GenreModel) Normalized() GenreModel {
+	var sum float64
+	for _, w := range m {
+		sum += w
+	}
+
+	res := make(GenreModel, len(m))
+	if sum <= 0 {
+		return res
+	}
+	for g, w := range m {
+		res[g] = w / sum
+	}
+	return res
+}
 
 var AllowedGenres = []string{
 	"classical",
@@ -0,0 +1,332 @@
+package hls
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	uploadfile "tracker-backend/internal/pkg/file"
+	"tracker-backend/internal/pkg/storage"
+	"tracker-backend/internal/track"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// bitrates of hls variants in kbit/s,
+// variants above source bitrate are skipped
+var variantBitrates = []int{64, 128, 192}
+
+const (
+	// tracks transcoded by one job run
+	batchSize = 10
+	// failed track is retried until attempts are exhausted
+	maxAttempts = 3
+	// processing track is picked again after timeout, e.g. after crash,
+	// it must be longer than transcoder job timeout
+	processingTimeout = time.Hour
+	// tail of ffmpeg output kept in error
+	maxStderrTail = 512
+)
+
+// HLSService transcodes uploaded audio to hls playlists and segments
+type HLSService struct {
+	tracksCol  *mongo.Collection
+	blobStore  storage.BlobStore
+	ffmpegPath string
+}
+
+func NewHLSService(
+	tracksCol *mongo.Collection, blobStore storage.BlobStore, ffmpegPath string,
+) *HLSService {
+	return &HLSService{
+		tracksCol:  tracksCol,
+		blobStore:  blobStore,
+		ffmpegPath: ffmpegPath,
+	}
+}
+
+// TranscodePending transcodes tracks waiting for hls one by one,
+// tracks uploaded before hls are transcoded as well
+func (s *HLSService) TranscodePending(ctx context.Context) error {
+	// configure logger
+	logger := slog.With(slog.String("function", "hls.HLSService.TranscodePending"))
+
+	if err := s.failStale(ctx); err != nil {
+		logger.Warn("failed to mark stale tracks", slog.String("error", err.Error()))
+		return errors.New("failed to mark stale tracks")
+	}
+
+	for range batchSize {
+		t, err := s.claim(ctx)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil
+			}
+			logger.Warn("failed to claim track", slog.String("error", err.Error()))
+			return errors.New("failed to claim track")
+		}
+
+		err = s.transcode(ctx, t)
+		if err := s.finish(ctx, t, err); err != nil {
+			logger.Warn("failed to update hls status",
+				slog.String("trackID", t.ID),
+				slog.String("error", err.Error()),
+			)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// failStale marks tracks stuck in processing without attempts left as failed
+func (s *HLSService) failStale(ctx context.Context) error {
+	_, err := s.tracksCol.UpdateMany(ctx,
+		bson.M{
+			"hlsStatus":    track.HLSStatusProcessing,
+			"hlsUpdatedAt": bson.M{"$lt": time.Now().Add(-processingTimeout)},
+			"hlsAttempts":  bson.M{"$gte": maxAttempts},
+		},
+		bson.M{"$set": bson.M{
+			"hlsStatus":    track.HLSStatusFailed,
+			"hlsUpdatedAt": time.Now(),
+		}},
+	)
+	return err
+}
+
+// claim marks the oldest waiting track as processing and returns it
+func (s *HLSService) claim(ctx context.Context) (*track.Track, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"hlsStatus": bson.M{"$exists": false}},
+		bson.M{"hlsStatus": track.HLSStatusPending},
+		bson.M{
+			"hlsStatus":    track.HLSStatusProcessing,
+			"hlsUpdatedAt": bson.M{"$lt": time.Now().Add(-processingTimeout)},
+		},
+	}}
+	update := bson.M{
+		"$set": bson.M{
+			"hlsStatus":    track.HLSStatusProcessing,
+			"hlsUpdatedAt": time.Now(),
+		},
+		"$inc": bson.M{"hlsAttempts": 1},
+	}
+
+	var t track.Track
+	err := s.tracksCol.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "hlsUpdatedAt", Value: 1}}).
+			SetReturnDocument(options.After),
+	).Decode(&t)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// finish sets result status of claimed track,
+// files of audio replaced during transcoding are removed
+func (s *HLSService) finish(ctx context.Context, t *track.Track, transcodeErr error) error {
+	// configure logger
+	logger := slog.With(slog.String("function", "hls.HLSService.finish"))
+
+	status := track.HLSStatusReady
+	if transcodeErr != nil {
+		status = track.HLSStatusPending
+		if t.HLSAttempts >= maxAttempts {
+			status = track.HLSStatusFailed
+		}
+		logger.Warn("failed to transcode track",
+			slog.String("trackID", t.ID),
+			slog.Int("attempt", t.HLSAttempts),
+			slog.String("error", transcodeErr.Error()),
+		)
+	}
+
+	res, err := s.tracksCol.UpdateOne(ctx,
+		bson.M{
+			"id":        t.ID,
+			"audioFile": t.AudioFile,
+			"hlsStatus": track.HLSStatusProcessing,
+		},
+		bson.M{"$set": bson.M{
+			"hlsStatus":    status,
+			"hlsUpdatedAt": time.Now(),
+		}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount < 1 {
+		// track was deleted or got new audio
+		return s.removeFiles(ctx, t)
+	}
+
+	if status == track.HLSStatusReady {
+		logger.Info("track transcoded", slog.String("trackID", t.ID))
+	}
+	return nil
+}
+
+// transcode segments track audio with ffmpeg and uploads results to blob store
+func (s *HLSService) transcode(ctx context.Context, t *track.Track) error {
+	tmpDir, err := os.MkdirTemp("", "hls-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// ffmpeg reads local file only
+	source := filepath.Join(tmpDir, "source"+path.Ext(t.AudioFile))
+	if err := s.download(ctx, t.AudioKey(), source); err != nil {
+		return fmt.Errorf("failed to download audio: %w", err)
+	}
+
+	outDir := filepath.Join(tmpDir, "out")
+	if err := os.Mkdir(outDir, 0755); err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.ffmpegPath, ffmpegArgs(t, source, outDir)...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		out := stderr.String()
+		if len(out) > maxStderrTail {
+			out = out[len(out)-maxStderrTail:]
+		}
+		return fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(out))
+	}
+
+	return s.upload(ctx, t, outDir)
+}
+
+func (s *HLSService) download(ctx context.Context, key, dst string) error {
+	blob, err := s.blobStore.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+
+	file, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, blob)
+	return err
+}
+
+// upload puts ffmpeg output to track hls dir,
+// master playlist is put last so it never refers to missing files
+func (s *HLSService) upload(ctx context.Context, t *track.Track, outDir string) error {
+	entries, err := os.ReadDir(outDir)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && entry.Name() != track.HLSMasterPlaylist {
+			names = append(names, entry.Name())
+		}
+	}
+	names = append(names, track.HLSMasterPlaylist)
+
+	for _, name := range names {
+		if err := s.put(ctx, path.Join(t.HLSDir(), name), filepath.Join(outDir, name)); err != nil {
+			return fmt.Errorf("failed to upload %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *HLSService) put(ctx context.Context, key, src string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	contentType := uploadfile.GetHLSContentTypeByExtension(path.Ext(key))
+	return s.blobStore.Put(ctx, key, file, info.Size(), contentType)
+}
+
+// removeFiles deletes hls files of track audio
+func (s *HLSService) removeFiles(ctx context.Context, t *track.Track) error {
+	blobs, err := s.blobStore.List(ctx, t.HLSDir()+"/")
+	if err != nil {
+		return err
+	}
+	for _, blob := range blobs {
+		if err := s.blobStore.Delete(ctx, blob.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ffmpegArgs builds ffmpeg arguments writing all variants in one run,
+// output layout is flat: master.m3u8, 128k.m3u8, 128k_000.ts, ...
+func ffmpegArgs(t *track.Track, source, outDir string) []string {
+	args := []string{"-hide_banner", "-loglevel", "error", "-y", "-i", source}
+
+	bitrates := variants(t)
+	streamMap := make([]string, len(bitrates))
+	for i, bitrate := range bitrates {
+		args = append(args,
+			"-map", "0:a:0",
+			fmt.Sprintf("-b:a:%d", i), fmt.Sprintf("%dk", bitrate),
+		)
+		streamMap[i] = fmt.Sprintf("a:%d,name:%dk", i, bitrate)
+	}
+
+	return append(args,
+		"-c:a", "aac",
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(track.HLSSegmentDuration),
+		"-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(outDir, "%v_%03d.ts"),
+		"-master_pl_name", track.HLSMasterPlaylist,
+		"-var_stream_map", strings.Join(streamMap, " "),
+		filepath.Join(outDir, "%v.m3u8"),
+	)
+}
+
+// variants returns bitrates not exceeding source bitrate,
+// the lowest one is always kept
+func variants(t *track.Track) []int {
+	bitrates := []int{}
+	for _, bitrate := range variantBitrates {
+		if t.Bitrate <= 0 || bitrate*1000 <= t.Bitrate {
+			bitrates = append(bitrates, bitrate)
+		}
+	}
+	if len(bitrates) == 0 {
+		bitrates = append(bitrates, variantBitrates[0])
+	}
+	return bitrates
+}
@@ -0,0 +1,159 @@
+package listen
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+	"tracker-backend/internal/genre"
+	listenType "tracker-backend/internal/listen/type"
+	"tracker-backend/internal/pkg/storage"
+	"tracker-backend/internal/track"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const (
+	// playback longer than threshold is counted as listen
+	listenThreshold = 30 * time.Second
+	// session state outlives track playback by this time
+	sessionGrace = 5 * time.Minute
+
+	listenKeyPrefix = "listen:"
+)
+
+// GenreModelBuilder adds genres of listened tracks to user genre model
+type GenreModelBuilder interface {
+	AddTrack(ctx context.Context, userID, trackID string, weight float64) error
+}
+
+type ListenService struct {
+	listensCol        *mongo.Collection
+	tracksCol         *mongo.Collection
+	redis             *storage.RedisClient
+	genreModelBuilder GenreModelBuilder
+}
+
+func NewListenService(
+	listensCol, tracksCol *mongo.Collection, redis *storage.RedisClient,
+	genreModelBuilder GenreModelBuilder,
+) *ListenService {
+	return &ListenService{
+		listensCol:        listensCol,
+		tracksCol:         tracksCol,
+		redis:             redis,
+		genreModelBuilder: genreModelBuilder,
+	}
+}
+
+// RecordProgress adds served bytes to listening session of track.
+// Listen is recorded once per session when served bytes cover
+// listen threshold or the last byte of track is served.
+// Range requests of the same session are summed up in redis
+func (s *ListenService) RecordProgress(
+	ctx context.Context, progress track.StreamProgress,
+) error {
+	// configure logger
+	logger := slog.With(slog.String("function", "listen.ListenService.RecordProgress"))
+
+	if progress.Served <= 0 || progress.FileSize <= 0 {
+		return nil
+	}
+
+	key := listenKeyPrefix + progress.SessionKey + ":" + progress.Track.ID
+	ttl := time.Duration(progress.Track.Duration)*time.Second + sessionGrace
+
+	// sum up bytes served in session
+	served, err := s.redis.IncrBy(ctx, key+":bytes", progress.Served, ttl)
+	if err != nil {
+		return errors.New("failed to update listening session")
+	}
+
+	completed := progress.Start+progress.Served >= progress.FileSize
+	if served < thresholdBytes(progress) && !completed {
+		return nil
+	}
+
+	// only the first request crossing threshold records listen
+	listenID := uuid.NewString()
+	created, err := s.redis.SetStringNX(ctx, key+":id", listenID, ttl)
+	if err != nil {
+		return errors.New("failed to update listening session")
+	}
+	if !created {
+		if !completed {
+			return nil
+		}
+		return s.complete(ctx, key)
+	}
+
+	listen := &listenType.Listen{
+		ID:        listenID,
+		UserID:    progress.UserID,
+		TrackID:   progress.Track.ID,
+		AlbumID:   progress.Track.AlbumID,
+		Completed: completed,
+		CreatedAt: time.Now(),
+	}
+	if _, err := s.listensCol.InsertOne(ctx, listen); err != nil {
+		// let next request of session record listen
+		s.redis.Delete(ctx, key+":id")
+		return errors.New("failed to insert listen")
+	}
+
+	// update track play counter
+	_, err = s.tracksCol.UpdateOne(ctx,
+		bson.M{"id": progress.Track.ID},
+		bson.M{"$inc": bson.M{"playCount": 1}},
+	)
+	if err != nil {
+		logger.Warn("failed to update play count", slog.String("error", err.Error()))
+	}
+
+	logger.Info("listen recorded",
+		slog.Group("info",
+			slog.String("id", listen.ID),
+			slog.String("userID", listen.UserID),
+			slog.String("trackID", listen.TrackID),
+			slog.Bool("completed", listen.Completed),
+		),
+	)
+
+	// recorded listen of authorized user updates genre model
+	if listen.UserID != "" {
+		err := s.genreModelBuilder.AddTrack(ctx, listen.UserID, listen.TrackID, genre.WeightListen)
+		if err != nil {
+			logger.Warn("failed to update genre model", slog.String("error", err.Error()))
+		}
+	}
+
+	return nil
+}
+
+// complete marks listen recorded in session as completed
+func (s *ListenService) complete(ctx context.Context, key string) error {
+	listenID, err := s.redis.GetString(ctx, key+":id")
+	if err != nil {
+		return errors.New("failed to get listening session")
+	}
+
+	_, err = s.listensCol.UpdateOne(ctx,
+		bson.M{"id": listenID, "completed": false},
+		bson.M{"$set": bson.M{"completed": true}},
+	)
+	if err != nil {
+		return errors.New("failed to update listen")
+	}
+	return nil
+}
+
+// thresholdBytes returns number of bytes covering listen threshold
+func thresholdBytes(progress track.StreamProgress) int64 {
+	duration := time.Duration(progress.Track.Duration) * time.Second
+	if duration <= listenThreshold {
+		return progress.FileSize
+	}
+	return int64(float64(progress.FileSize) * listenThreshold.Seconds() / duration.Seconds())
+}
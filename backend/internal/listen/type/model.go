@@ -0,0 +1,42 @@
+package listenType
+
+import (
+	"time"
+	"tracker-backend/internal/track"
+)
+
+// HistoryItem is a listen joined with its track,
+// track is nil if it was deleted
+type HistoryItem struct {
+	Listen `bson:",inline"`
+	Track  *track.Track `bson:"track"`
+}
+
+type HistoryItemResponse struct {
+	ID         string               `json:"id"`
+	TrackID    string               `json:"trackID"`
+	Completed  bool                 `json:"completed"`
+	ListenedAt string               `json:"listenedAt"`
+	Track      *track.TrackResponse `json:"track"`
+}
+
+type HistoryListResponse struct {
+	Items []HistoryItemResponse `json:"items"`
+	Total int64                 `json:"total"`
+	Page  int64                 `json:"page"`
+	Limit int64                 `json:"limit"`
+}
+
+func (h *HistoryItem) ToResponse() HistoryItemResponse {
+	res := HistoryItemResponse{
+		ID:         h.ID,
+		TrackID:    h.TrackID,
+		Completed:  h.Completed,
+		ListenedAt: h.CreatedAt.Format(time.RFC3339),
+	}
+	if h.Track != nil {
+		t := h.Track.ToResponse()
+		res.Track = &t
+	}
+	return res
+}
@@ -0,0 +1,49 @@
+package listenType
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Listen is a single play of track
+type Listen struct {
+	ID        string    `bson:"id"`
+	UserID    string    `bson:"userID"` // empty for anonymous listeners
+	TrackID   string    `bson:"trackID"`
+	AlbumID   string    `bson:"albumID"`
+	Completed bool      `bson:"completed"` // last byte of track was served
+	CreatedAt time.Time `bson:"createdAt"`
+}
+
+func EnsureIndexes(ctx context.Context, col *mongo.Collection) error {
+	// index for user history
+	userIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "userID", Value: 1},
+			{Key: "createdAt", Value: -1},
+		},
+		Options: options.Index().SetName("userID_createdAt"),
+	}
+
+	// index for track statistics
+	trackIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "trackID", Value: 1},
+			{Key: "createdAt", Value: -1},
+		},
+		Options: options.Index().SetName("trackID_createdAt"),
+	}
+
+	// unique index by id string
+	idIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("id_unique"),
+	}
+
+	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{userIndex, trackIndex, idIndex})
+	return err
+}
@@ -0,0 +1,198 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+	"tracker-backend/internal/config"
+	"tracker-backend/internal/pkg/storage"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// what gc does with unreferenced files
+const (
+	ModeDelete     = "delete"
+	ModeQuarantine = "quarantine"
+	ModeDryRun     = "dry-run"
+)
+
+const (
+	// default images are shared and never collected
+	defaultFileMarker = "_default"
+)
+
+// GCReport is a result of one gc run
+type GCReport struct {
+	Scanned   int64
+	Orphaned  int64
+	Collected int64
+	Bytes     int64 // size of orphaned files
+}
+
+// MediaGCService collects uploaded files
+// not referenced by tracks, albums and artists
+type MediaGCService struct {
+	tracksCol  *mongo.Collection
+	albumsCol  *mongo.Collection
+	artistsCol *mongo.Collection
+	blobStore  storage.BlobStore
+	mode       string
+	grace      time.Duration
+}
+
+// NewMediaGCService creates gc of media files,
+// files younger than grace period may be not referenced yet
+func NewMediaGCService(
+	tracksCol, albumsCol, artistsCol *mongo.Collection,
+	blobStore storage.BlobStore,
+	mode string, grace time.Duration,
+) *MediaGCService {
+	return &MediaGCService{
+		tracksCol:  tracksCol,
+		albumsCol:  albumsCol,
+		artistsCol: artistsCol,
+		blobStore:  blobStore,
+		mode:       mode,
+		grace:      grace,
+	}
+}
+
+// Collect removes, quarantines or only reports unreferenced media files
+func (s *MediaGCService) Collect(ctx context.Context) (*GCReport, error) {
+	// configure logger
+	logger := slog.With(slog.String("function", "media.MediaGCService.Collect"))
+
+	// references are read before files are listed,
+	// new uploads are protected by grace period
+	// hls files are grouped in dirs named by audio file without extension
+	dirs := []struct {
+		dir    string
+		col    *mongo.Collection
+		field  string
+		nested bool
+	}{
+		{config.AudioDir, s.tracksCol, "audioFile", false},
+		{config.CoversDir, s.albumsCol, "coverPath", false},
+		{config.AvatarsDir, s.artistsCol, "avatarPath", false},
+		{config.HLSDir, s.tracksCol, "audioFile", true},
+	}
+
+	report := &GCReport{}
+	for _, d := range dirs {
+		refs, err := s.references(ctx, d.col, d.field, d.nested)
+		if err != nil {
+			logger.Warn("failed to get references",
+				slog.String("dir", d.dir),
+				slog.String("error", err.Error()),
+			)
+			return report, errors.New("failed to get file references")
+		}
+
+		if err := s.collectDir(ctx, d.dir, d.nested, refs, report); err != nil {
+			logger.Warn("failed to collect files",
+				slog.String("dir", d.dir),
+				slog.String("error", err.Error()),
+			)
+			return report, errors.New("failed to collect files")
+		}
+	}
+
+	logger.Info("media gc finished",
+		slog.Group("info",
+			slog.String("mode", s.mode),
+			slog.Int64("scanned", report.Scanned),
+			slog.Int64("orphaned", report.Orphaned),
+			slog.Int64("collected", report.Collected),
+			slog.Int64("bytes", report.Bytes),
+		),
+	)
+
+	return report, nil
+}
+
+// references returns names of files referenced by field of collection,
+// paths are compared by file name as uploaded files have unique names.
+// Names of nested dirs have no extension
+func (s *MediaGCService) references(
+	ctx context.Context, col *mongo.Collection, field string, nested bool,
+) (map[string]bool, error) {
+	var paths []string
+	if err := col.Distinct(ctx, field, bson.M{}).Decode(&paths); err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		name := path.Base(p)
+		if nested {
+			name = strings.TrimSuffix(name, path.Ext(name))
+		}
+		refs[name] = true
+	}
+	return refs, nil
+}
+
+func (s *MediaGCService) collectDir(
+	ctx context.Context, dir string, nested bool, refs map[string]bool, report *GCReport,
+) error {
+	// configure logger
+	logger := slog.With(slog.String("function", "media.MediaGCService.collectDir"))
+
+	blobs, err := s.blobStore.List(ctx, dir+"/")
+	if err != nil {
+		return err
+	}
+
+	for _, blob := range blobs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		report.Scanned++
+
+		// files of nested dir are referenced by dir name
+		name := path.Base(blob.Key)
+		if nested {
+			name, _, _ = strings.Cut(strings.TrimPrefix(blob.Key, dir+"/"), "/")
+		}
+		if refs[name] || strings.Contains(name, defaultFileMarker) {
+			continue
+		}
+		if time.Since(blob.ModTime) < s.grace {
+			continue
+		}
+
+		report.Orphaned++
+		report.Bytes += blob.Size
+
+		switch s.mode {
+		case ModeDelete:
+			err = s.blobStore.Delete(ctx, blob.Key)
+		case ModeQuarantine:
+			// keep relative key under quarantine dir
+			err = s.blobStore.Move(ctx, blob.Key, path.Join(config.QuarantineDir, blob.Key))
+		}
+		if err != nil {
+			logger.Warn("failed to collect file",
+				slog.String("key", blob.Key),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		if s.mode != ModeDryRun {
+			report.Collected++
+		}
+
+		logger.Info("orphaned file",
+			slog.String("mode", s.mode),
+			slog.String("key", blob.Key),
+			slog.Int64("size", blob.Size),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package audioprobe
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// mp4Atom is a box of MP4 container
+type mp4Atom struct {
+	typ   string
+	start int64 // offset of atom body
+	end   int64
+}
+
+// probeM4A reads duration from "moov/mvhd" atom and audio parameters
+// from first audio sample description of "moov/trak/.../stsd"
+func probeM4A(r io.ReaderAt, size int64) (*Info, error) {
+	info := &Info{Format: FormatM4A}
+
+	moov, err := findAtom(r, 0, size, "moov")
+	if err != nil {
+		return nil, err
+	}
+
+	// movie header
+	mvhd, err := findAtom(r, moov.start, moov.end, "mvhd")
+	if err != nil {
+		return nil, err
+	}
+	timescale, duration, err := readMVHD(r, mvhd)
+	if err != nil {
+		return nil, err
+	}
+	info.Duration = time.Duration(float64(duration) / float64(timescale) * float64(time.Second))
+
+	// find audio track sample description
+	found := false
+	err = eachAtom(r, moov.start, moov.end, func(trak mp4Atom) (bool, error) {
+		if trak.typ != "trak" {
+			return false, nil
+		}
+		stsd, err := findAtom(r, trak.start, trak.end, "mdia", "minf", "stbl", "stsd")
+		if err != nil {
+			// not a media track
+			return false, nil
+		}
+		found, err = readAudioSampleEntry(r, stsd, info)
+		return found, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrMalformed
+	}
+
+	// media data size
+	dataSize := size
+	if mdat, err := findAtom(r, 0, size, "mdat"); err == nil {
+		dataSize = mdat.end - mdat.start
+	}
+	info.Bitrate = bitrateOf(dataSize, info.Duration)
+
+	return info, nil
+}
+
+// readMVHD returns timescale and duration of movie header
+func readMVHD(r io.ReaderAt, mvhd mp4Atom) (int64, int64, error) {
+	versionBuf := make([]byte, 1)
+	if err := readAt(r, versionBuf, mvhd.start); err != nil {
+		return 0, 0, err
+	}
+
+	var timescale, duration int64
+	if versionBuf[0] == 1 {
+		// version, flags, creation and modification time (64 bit)
+		buf := make([]byte, 12)
+		if err := readAt(r, buf, mvhd.start+4+16); err != nil {
+			return 0, 0, err
+		}
+		timescale = int64(binary.BigEndian.Uint32(buf[0:4]))
+		duration = int64(binary.BigEndian.Uint64(buf[4:12]))
+	} else {
+		// version, flags, creation and modification time (32 bit)
+		buf := make([]byte, 8)
+		if err := readAt(r, buf, mvhd.start+4+8); err != nil {
+			return 0, 0, err
+		}
+		timescale = int64(binary.BigEndian.Uint32(buf[0:4]))
+		duration = int64(binary.BigEndian.Uint32(buf[4:8]))
+	}
+
+	if timescale == 0 {
+		return 0, 0, ErrMalformed
+	}
+
+	return timescale, duration, nil
+}
+
+// readAudioSampleEntry reads channels and sample rate of first
+// audio sample entry of "stsd" atom
+func readAudioSampleEntry(r io.ReaderAt, stsd mp4Atom, info *Info) (bool, error) {
+	// skip version, flags and entries count
+	found := false
+	err := eachAtom(r, stsd.start+8, stsd.end, func(entry mp4Atom) (bool, error) {
+		if entry.typ != "mp4a" && entry.typ != "alac" {
+			return false, nil
+		}
+		// reserved (6), data reference index (2), version (2), revision (2),
+		// vendor (4), channels (2), sample size (2), compression id (2),
+		// packet size (2), sample rate (16.16 fixed point)
+		buf := make([]byte, 28)
+		if err := readAt(r, buf, entry.start); err != nil {
+			return false, err
+		}
+		info.Channels = int(binary.BigEndian.Uint16(buf[16:18]))
+		info.SampleRate = int(binary.BigEndian.Uint32(buf[24:28]) >> 16)
+		found = true
+		return true, nil
+	})
+
+	return found, err
+}
+
+// findAtom finds nested atom by path inside [start, end) range
+func findAtom(r io.ReaderAt, start, end int64, path ...string) (mp4Atom, error) {
+	var (
+		result mp4Atom
+		found  bool
+	)
+	err := eachAtom(r, start, end, func(a mp4Atom) (bool, error) {
+		if a.typ == path[0] {
+			result, found = a, true
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return mp4Atom{}, err
+	}
+	if !found {
+		return mp4Atom{}, ErrMalformed
+	}
+
+	if len(path) > 1 {
+		return findAtom(r, result.start, result.end, path[1:]...)
+	}
+	return result, nil
+}
+
+// eachAtom calls fn for every atom in [start, end) range
+// iteration stops when fn returns true or error
+func eachAtom(
+	r io.ReaderAt, start, end int64, fn func(a mp4Atom) (bool, error),
+) error {
+	header := make([]byte, 16)
+	for offset := start; offset+8 <= end; {
+		if err := readAt(r, header[:8], offset); err != nil {
+			return err
+		}
+		atomSize := int64(binary.BigEndian.Uint32(header[0:4]))
+		a := mp4Atom{typ: string(header[4:8]), start: offset + 8}
+
+		switch atomSize {
+		case 0:
+			// atom extends to the end of range
+			atomSize = end - offset
+		case 1:
+			// 64 bit size follows type
+			if err := readAt(r, header[8:16], offset+8); err != nil {
+				return err
+			}
+			atomSize = int64(binary.BigEndian.Uint64(header[8:16]))
+			a.start += 8
+		}
+
+		if atomSize < a.start-offset || offset+atomSize > end {
+			return ErrMalformed
+		}
+		a.end = offset + atomSize
+
+		stop, err := fn(a)
+		if err != nil || stop {
+			return err
+		}
+		offset = a.end
+	}
+
+	return nil
+}
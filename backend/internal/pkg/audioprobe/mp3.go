@@ -0,0 +1,293 @@
+package audioprobe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+const (
+	mpegVersion25 = 0
+	mpegVersion2  = 2
+	mpegVersion1  = 3
+
+	mpegLayer3 = 1
+	mpegLayer2 = 2
+	mpegLayer1 = 3
+
+	// max bytes scanned to find first frame after tags
+	mp3SyncSearchLimit = 64 * 1024
+)
+
+// bitrates in kbps by [version is MPEG1][layer][index]
+var mp3Bitrates = [2][4][16]int{
+	// MPEG2, MPEG2.5
+	{
+		{},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},      // layer III
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},      // layer II
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0}, // layer I
+	},
+	// MPEG1
+	{
+		{},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},     // layer III
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},    // layer II
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0}, // layer I
+	},
+}
+
+// sample rates by [version][index]
+var mp3SampleRates = [4][3]int{
+	mpegVersion25: {11025, 12000, 8000},
+	mpegVersion2:  {22050, 24000, 16000},
+	mpegVersion1:  {44100, 48000, 32000},
+}
+
+// mp3Frame is a parsed MPEG audio frame header
+type mp3Frame struct {
+	version         int
+	layer           int
+	bitrate         int // bits per second
+	sampleRate      int
+	channels        int
+	samplesPerFrame int
+	size            int64 // frame size in bytes including header
+}
+
+// parseMP3Frame parses 4 byte frame header
+func parseMP3Frame(h []byte) (*mp3Frame, bool) {
+	if len(h) < 4 || h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+		return nil, false
+	}
+
+	version := int(h[1]>>3) & 0x03
+	layer := int(h[1]>>1) & 0x03
+	bitrateIndex := int(h[2] >> 4)
+	sampleRateIndex := int(h[2]>>2) & 0x03
+	padding := int64(h[2]>>1) & 0x01
+	channelMode := int(h[3] >> 6)
+
+	// reserved and "free format" values are not supported
+	if version == 1 || layer == 0 || bitrateIndex == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+		return nil, false
+	}
+
+	isV1 := 0
+	if version == mpegVersion1 {
+		isV1 = 1
+	}
+
+	f := &mp3Frame{
+		version:    version,
+		layer:      layer,
+		bitrate:    mp3Bitrates[isV1][layer][bitrateIndex] * 1000,
+		sampleRate: mp3SampleRates[version][sampleRateIndex],
+		channels:   2,
+	}
+	if channelMode == 3 {
+		f.channels = 1
+	}
+
+	switch {
+	case layer == mpegLayer1:
+		f.samplesPerFrame = 384
+		f.size = (12*int64(f.bitrate)/int64(f.sampleRate) + padding) * 4
+	case layer == mpegLayer3 && version != mpegVersion1:
+		f.samplesPerFrame = 576
+		f.size = 72*int64(f.bitrate)/int64(f.sampleRate) + padding
+	default:
+		f.samplesPerFrame = 1152
+		f.size = 144*int64(f.bitrate)/int64(f.sampleRate) + padding
+	}
+
+	return f, f.size > 4
+}
+
+// sideInfoSize returns size of layer III side information
+func (f *mp3Frame) sideInfoSize() int {
+	if f.version == mpegVersion1 {
+		if f.channels == 1 {
+			return 17
+		}
+		return 32
+	}
+	if f.channels == 1 {
+		return 9
+	}
+	return 17
+}
+
+// probeMP3 finds first MPEG frame and computes duration
+// by Xing/Info or VBRI header, or by frame bitrate for CBR files
+func probeMP3(r io.ReaderAt, size int64) (*Info, error) {
+	start, err := skipID3v2(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	// ID3v1 tag at the end of file is not audio data
+	end := size
+	if size >= 128 {
+		tag := make([]byte, 3)
+		if err := readAt(r, tag, size-128); err != nil {
+			return nil, err
+		}
+		if bytes.Equal(tag, []byte("TAG")) {
+			end = size - 128
+		}
+	}
+
+	offset, frame, err := findFirstMP3Frame(r, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &Info{
+		Format:     FormatMP3,
+		SampleRate: frame.sampleRate,
+		Channels:   frame.channels,
+	}
+
+	// read first frame to look for VBR headers
+	buf := make([]byte, min(frame.size, end-offset))
+	if err := readAt(r, buf, offset); err != nil {
+		return nil, err
+	}
+
+	frames, dataSize := parseXing(buf, frame)
+	if frames == 0 {
+		frames, dataSize = parseVBRI(buf)
+	}
+
+	if frames > 0 {
+		// VBR file, duration by frames count
+		if dataSize == 0 {
+			dataSize = end - offset
+		}
+		info.Duration = durationOf(frames*int64(frame.samplesPerFrame), frame.sampleRate)
+		info.Bitrate = bitrateOf(dataSize, info.Duration)
+		return info, nil
+	}
+
+	// CBR file, duration by bitrate
+	info.Bitrate = frame.bitrate
+	info.Duration = time.Duration(float64((end-offset)*8) / float64(frame.bitrate) * float64(time.Second))
+
+	return info, nil
+}
+
+// skipID3v2 returns offset of data after ID3v2 tag
+func skipID3v2(r io.ReaderAt, size int64) (int64, error) {
+	header := make([]byte, 10)
+	if size < 10 {
+		return 0, ErrMalformed
+	}
+	if err := readAt(r, header, 0); err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(header[0:3], []byte("ID3")) {
+		return 0, nil
+	}
+
+	// tag size is stored as syncsafe integer
+	tagSize := int64(header[6]&0x7F)<<21 |
+		int64(header[7]&0x7F)<<14 |
+		int64(header[8]&0x7F)<<7 |
+		int64(header[9]&0x7F)
+	offset := 10 + tagSize
+	// footer present
+	if header[5]&0x10 != 0 {
+		offset += 10
+	}
+	if offset >= size {
+		return 0, ErrMalformed
+	}
+
+	return offset, nil
+}
+
+// findFirstMP3Frame scans data for frame sync
+// frame is accepted if it is followed by another valid frame
+func findFirstMP3Frame(r io.ReaderAt, start, end int64) (int64, *mp3Frame, error) {
+	buf := make([]byte, min(mp3SyncSearchLimit, end-start))
+	n, err := r.ReadAt(buf, start)
+	if n == 0 && err != nil {
+		return 0, nil, ErrMalformed
+	}
+	buf = buf[:n]
+
+	next := make([]byte, 4)
+	for i := 0; i+4 <= len(buf); i++ {
+		frame, ok := parseMP3Frame(buf[i : i+4])
+		if !ok {
+			continue
+		}
+
+		offset := start + int64(i)
+		nextOffset := offset + frame.size
+		// single frame file
+		if nextOffset+4 > end {
+			return offset, frame, nil
+		}
+
+		if err := readAt(r, next, nextOffset); err != nil {
+			return 0, nil, err
+		}
+		nextFrame, ok := parseMP3Frame(next)
+		if ok && nextFrame.version == frame.version && nextFrame.layer == frame.layer &&
+			nextFrame.sampleRate == frame.sampleRate {
+			return offset, frame, nil
+		}
+	}
+
+	return 0, nil, ErrMalformed
+}
+
+// parseXing returns frames count and data size from Xing/Info header
+func parseXing(buf []byte, frame *mp3Frame) (int64, int64) {
+	if frame.layer != mpegLayer3 {
+		return 0, 0
+	}
+
+	pos := 4 + frame.sideInfoSize()
+	if len(buf) < pos+8 {
+		return 0, 0
+	}
+	tag := string(buf[pos : pos+4])
+	if tag != "Xing" && tag != "Info" {
+		return 0, 0
+	}
+
+	flags := binary.BigEndian.Uint32(buf[pos+4 : pos+8])
+	pos += 8
+
+	var frames, dataSize int64
+	if flags&0x01 != 0 {
+		if len(buf) < pos+4 {
+			return 0, 0
+		}
+		frames = int64(binary.BigEndian.Uint32(buf[pos : pos+4]))
+		pos += 4
+	}
+	if flags&0x02 != 0 && len(buf) >= pos+4 {
+		dataSize = int64(binary.BigEndian.Uint32(buf[pos : pos+4]))
+	}
+
+	return frames, dataSize
+}
+
+// parseVBRI returns frames count and data size from Fraunhofer VBRI header
+func parseVBRI(buf []byte) (int64, int64) {
+	// VBRI header is located 32 bytes after frame header
+	const pos = 4 + 32
+	if len(buf) < pos+18 || string(buf[pos:pos+4]) != "VBRI" {
+		return 0, 0
+	}
+
+	dataSize := int64(binary.BigEndian.Uint32(buf[pos+10 : pos+14]))
+	frames := int64(binary.BigEndian.Uint32(buf[pos+14 : pos+18]))
+
+	return frames, dataSize
+}
@@ -0,0 +1,123 @@
+package audioprobe
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	FormatMP3 = "mp3"
+	FormatWAV = "wav"
+	FormatM4A = "m4a"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported audio format")
+	ErrFormatMismatch    = errors.New("audio content does not match file extension")
+	ErrMalformed         = errors.New("malformed audio file")
+)
+
+// Info contains technical parameters of audio file
+type Info struct {
+	Format     string
+	Duration   time.Duration
+	SampleRate int // samples per second
+	Channels   int
+	Bitrate    int // bits per second
+}
+
+// Probe detects audio format by file content and reads its parameters
+func Probe(r io.ReaderAt, size int64) (*Info, error) {
+	// read file signature
+	head := make([]byte, 12)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	head = head[:n]
+
+	var info *Info
+	switch detectFormat(head) {
+	case FormatWAV:
+		info, err = probeWAV(r, size)
+	case FormatM4A:
+		info, err = probeM4A(r, size)
+	case FormatMP3:
+		info, err = probeMP3(r, size)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Duration <= 0 || info.SampleRate <= 0 || info.Channels <= 0 {
+		return nil, ErrMalformed
+	}
+
+	return info, nil
+}
+
+// ProbeWithExtension probes audio file and checks that its content
+// matches file extension (e.g. ".mp3")
+func ProbeWithExtension(r io.ReaderAt, size int64, ext string) (*Info, error) {
+	info, err := Probe(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimPrefix(strings.ToLower(ext), ".") != info.Format {
+		return nil, ErrFormatMismatch
+	}
+
+	return info, nil
+}
+
+// detectFormat returns format by file signature
+func detectFormat(head []byte) string {
+	switch {
+	case len(head) >= 12 &&
+		bytes.Equal(head[0:4], []byte("RIFF")) &&
+		bytes.Equal(head[8:12], []byte("WAVE")):
+		return FormatWAV
+	case len(head) >= 8 && bytes.Equal(head[4:8], []byte("ftyp")):
+		return FormatM4A
+	case len(head) >= 3 && bytes.Equal(head[0:3], []byte("ID3")):
+		return FormatMP3
+	case len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0:
+		return FormatMP3
+	default:
+		return ""
+	}
+}
+
+// readAt reads exactly len(buf) bytes at offset
+func readAt(r io.ReaderAt, buf []byte, off int64) error {
+	n, err := r.ReadAt(buf, off)
+	if n == len(buf) {
+		return nil
+	}
+	if err == nil || errors.Is(err, io.EOF) {
+		return ErrMalformed
+	}
+	return err
+}
+
+// durationOf converts number of samples to duration
+func durationOf(samples int64, sampleRate int) time.Duration {
+	if sampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(samples * int64(time.Second) / int64(sampleRate))
+}
+
+// bitrateOf returns average bitrate of data with given size and duration
+func bitrateOf(dataSize int64, duration time.Duration) int {
+	if duration <= 0 {
+		return 0
+	}
+	return int(float64(dataSize*8) / duration.Seconds())
+}
@@ -0,0 +1,260 @@
+package audioprobe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+// mpeg1 layer III frame headers, 128 kbps, stereo
+var (
+	mp3Header44100 = []byte{0xFF, 0xFB, 0x90, 0x00} // 417 bytes frame
+	mp3Header48000 = []byte{0xFF, 0xFB, 0x94, 0x00} // 384 bytes frame
+)
+
+// mp3Frames returns n frames with header and empty body
+func mp3Frames(header []byte, frameSize, n int) []byte {
+	var buf bytes.Buffer
+	for range n {
+		frame := make([]byte, frameSize)
+		copy(frame, header)
+		buf.Write(frame)
+	}
+	return buf.Bytes()
+}
+
+// xingMP3 returns VBR file with Xing header in the first frame
+func xingMP3(frames, dataSize uint32) []byte {
+	first := make([]byte, 384)
+	copy(first, mp3Header48000)
+	// Xing header follows side info of stereo MPEG1 frame
+	pos := 4 + 32
+	copy(first[pos:], "Xing")
+	binary.BigEndian.PutUint32(first[pos+4:], 0x03) // frames and bytes
+	binary.BigEndian.PutUint32(first[pos+8:], frames)
+	binary.BigEndian.PutUint32(first[pos+12:], dataSize)
+
+	return append(first, mp3Frames(mp3Header48000, 384, 2)...)
+}
+
+// id3v2 returns ID3v2 tag with body of size bytes
+func id3v2(size int) []byte {
+	tag := []byte{'I', 'D', '3', 4, 0, 0,
+		byte(size >> 21 & 0x7F), byte(size >> 14 & 0x7F), byte(size >> 7 & 0x7F), byte(size & 0x7F),
+	}
+	return append(tag, make([]byte, size)...)
+}
+
+// wav returns PCM file with dataSize bytes of samples
+func wav(channels, sampleRate, bitsPerSample, dataSize int) []byte {
+	blockAlign := channels * bitsPerSample / 8
+
+	fmtChunk := make([]byte, 16)
+	binary.LittleEndian.PutUint16(fmtChunk[0:], 1) // PCM
+	binary.LittleEndian.PutUint16(fmtChunk[2:], uint16(channels))
+	binary.LittleEndian.PutUint32(fmtChunk[4:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(fmtChunk[8:], uint32(sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(fmtChunk[12:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(fmtChunk[14:], uint16(bitsPerSample))
+
+	body := append([]byte("WAVE"), riffChunk("fmt ", fmtChunk)...)
+	body = append(body, riffChunk("data", make([]byte, dataSize))...)
+	return riffChunk("RIFF", body)
+}
+
+func riffChunk(id string, body []byte) []byte {
+	chunk := make([]byte, 8, 8+len(body))
+	copy(chunk, id)
+	binary.LittleEndian.PutUint32(chunk[4:], uint32(len(body)))
+	return append(chunk, body...)
+}
+
+// atom returns MP4 atom with concatenated body parts
+func atom(typ string, body ...[]byte) []byte {
+	joined := bytes.Join(body, nil)
+	a := make([]byte, 8, 8+len(joined))
+	binary.BigEndian.PutUint32(a, uint32(8+len(joined)))
+	copy(a[4:], typ)
+	return append(a, joined...)
+}
+
+// mvhd returns movie header body of version 0 or 1
+func mvhd(version byte, timescale uint32, duration uint64) []byte {
+	if version == 1 {
+		body := make([]byte, 4+16+12+80)
+		body[0] = 1
+		binary.BigEndian.PutUint32(body[20:], timescale)
+		binary.BigEndian.PutUint64(body[24:], duration)
+		return body
+	}
+	body := make([]byte, 4+8+8+80)
+	binary.BigEndian.PutUint32(body[12:], timescale)
+	binary.BigEndian.PutUint32(body[16:], uint32(duration))
+	return body
+}
+
+// m4a returns file with one AAC track and mdatSize bytes of media data
+func m4a(header []byte, channels, sampleRate, mdatSize int) []byte {
+	entry := make([]byte, 28)
+	binary.BigEndian.PutUint16(entry[16:], uint16(channels))
+	binary.BigEndian.PutUint32(entry[24:], uint32(sampleRate)<<16)
+	// version, flags and entries count
+	stsdHeader := []byte{0, 0, 0, 0, 0, 0, 0, 1}
+
+	trak := atom("trak", atom("mdia", atom("minf", atom("stbl",
+		atom("stsd", stsdHeader, atom("mp4a", entry)),
+	))))
+	return bytes.Join([][]byte{
+		atom("ftyp", []byte("M4A "), make([]byte, 4)),
+		atom("moov", atom("mvhd", header), trak),
+		atom("mdat", make([]byte, mdatSize)),
+	}, nil)
+}
+
+func TestProbe(t *testing.T) {
+	cbr := mp3Frames(mp3Header48000, 384, 125)
+
+	tests := []struct {
+		name string
+		data []byte
+		want *Info
+		err  error
+	}{
+		{
+			name: "mp3 cbr",
+			data: cbr,
+			want: &Info{
+				Format: FormatMP3, SampleRate: 48000, Channels: 2, Bitrate: 128000,
+				// 125 frames of 384 bytes at 128 kbps
+				Duration: 3 * time.Second,
+			},
+		},
+		{
+			name: "mp3 cbr with id3v2 tag",
+			data: append(id3v2(1000), cbr...),
+			want: &Info{
+				Format: FormatMP3, SampleRate: 48000, Channels: 2, Bitrate: 128000,
+				Duration: 3 * time.Second,
+			},
+		},
+		{
+			name: "mp3 vbr with xing header",
+			// 1250 frames of 1152 samples at 48 kHz
+			data: xingMP3(1250, 480000),
+			want: &Info{
+				Format: FormatMP3, SampleRate: 48000, Channels: 2, Bitrate: 128000,
+				Duration: 30 * time.Second,
+			},
+		},
+		{
+			name: "wav pcm",
+			data: wav(2, 8000, 16, 32000*3),
+			want: &Info{
+				Format: FormatWAV, SampleRate: 8000, Channels: 2, Bitrate: 256000,
+				Duration: 3 * time.Second,
+			},
+		},
+		{
+			name: "m4a mvhd v0",
+			data: m4a(mvhd(0, 44100, 44100*3), 2, 44100, 3000),
+			want: &Info{
+				Format: FormatM4A, SampleRate: 44100, Channels: 2, Bitrate: 8000,
+				Duration: 3 * time.Second,
+			},
+		},
+		{
+			name: "m4a mvhd v1",
+			data: m4a(mvhd(1, 1000, 3000), 1, 48000, 3000),
+			want: &Info{
+				Format: FormatM4A, SampleRate: 48000, Channels: 1, Bitrate: 8000,
+				Duration: 3 * time.Second,
+			},
+		},
+		{name: "empty", data: nil, err: ErrUnsupportedFormat},
+		{name: "garbage", data: []byte("definitely not an audio file"), err: ErrUnsupportedFormat},
+		{name: "mp3 sync only", data: []byte{0xFF, 0xFB}, err: ErrMalformed},
+		{name: "mp3 without second frame", data: append(bytes.Clone(mp3Header44100), make([]byte, 1000)...), err: ErrMalformed},
+		{name: "id3v2 tag without audio", data: id3v2(1000)[:100], err: ErrMalformed},
+		{name: "wav without data", data: wav(2, 8000, 16, 32000)[:36], err: ErrMalformed},
+		{name: "wav with empty data", data: wav(2, 8000, 16, 0), err: ErrMalformed},
+		{name: "wav with zero byte rate", data: wav(0, 8000, 16, 32000), err: ErrMalformed},
+		{name: "m4a truncated moov", data: m4a(mvhd(0, 44100, 44100*3), 2, 44100, 3000)[:100], err: ErrMalformed},
+		{name: "m4a zero timescale", data: m4a(mvhd(0, 0, 44100*3), 2, 44100, 3000), err: ErrMalformed},
+		{name: "m4a without audio track", data: bytes.Join([][]byte{
+			atom("ftyp", []byte("M4A "), make([]byte, 4)),
+			atom("moov", atom("mvhd", mvhd(0, 44100, 44100*3))),
+		}, nil), err: ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Probe(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Probe error = %v, want %v", err, tt.err)
+			}
+			if tt.want == nil {
+				if got != nil {
+					t.Fatalf("Probe = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil || *got != *tt.want {
+				t.Fatalf("Probe = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestProbeTruncated checks that every prefix of valid file
+// is probed without panic
+func TestProbeTruncated(t *testing.T) {
+	files := map[string][]byte{
+		"mp3 cbr": append(id3v2(100), mp3Frames(mp3Header44100, 417, 3)...),
+		"mp3 vbr": xingMP3(1250, 480000),
+		"wav":     wav(1, 8000, 8, 100),
+		"m4a v0":  m4a(mvhd(0, 44100, 44100*3), 2, 44100, 100),
+		"m4a v1":  m4a(mvhd(1, 1000, 3000), 2, 44100, 100),
+	}
+
+	for name, data := range files {
+		t.Run(name, func(t *testing.T) {
+			for n := range len(data) {
+				info, err := Probe(bytes.NewReader(data[:n]), int64(n))
+				if err == nil && (info.Duration <= 0 || info.SampleRate <= 0 || info.Channels <= 0) {
+					t.Fatalf("Probe of %d bytes = %+v without error", n, info)
+				}
+			}
+		})
+	}
+}
+
+func TestProbeWithExtension(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		ext  string
+		err  error
+	}{
+		{name: "mp3", data: mp3Frames(mp3Header44100, 417, 10), ext: ".mp3"},
+		{name: "wav", data: wav(1, 8000, 8, 8000), ext: ".wav"},
+		{name: "m4a", data: m4a(mvhd(0, 1000, 3000), 2, 44100, 100), ext: ".m4a"},
+		{name: "upper case extension", data: wav(1, 8000, 8, 8000), ext: ".WAV"},
+		{name: "extension without dot", data: wav(1, 8000, 8, 8000), ext: "wav"},
+		{name: "wav named mp3", data: wav(1, 8000, 8, 8000), ext: ".mp3", err: ErrFormatMismatch},
+		{name: "mp3 named m4a", data: mp3Frames(mp3Header44100, 417, 10), ext: ".m4a", err: ErrFormatMismatch},
+		{name: "m4a named wav", data: m4a(mvhd(0, 1000, 3000), 2, 44100, 100), ext: ".wav", err: ErrFormatMismatch},
+		{name: "no extension", data: wav(1, 8000, 8, 8000), ext: "", err: ErrFormatMismatch},
+		{name: "garbage with audio extension", data: []byte("not audio"), ext: ".mp3", err: ErrUnsupportedFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ProbeWithExtension(bytes.NewReader(tt.data), int64(len(tt.data)), tt.ext)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ProbeWithExtension error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package audioprobe
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// probeWAV reads "fmt " and "data" chunks of RIFF/WAVE file
+func probeWAV(r io.ReaderAt, size int64) (*Info, error) {
+	var (
+		info       = &Info{Format: FormatWAV}
+		byteRate   int64
+		dataSize   int64
+		fmtFound   bool
+		dataFound  bool
+		chunkStart int64 = 12 // skip "RIFF" <size> "WAVE"
+	)
+
+	header := make([]byte, 8)
+	for chunkStart+8 <= size && !(fmtFound && dataFound) {
+		if err := readAt(r, header, chunkStart); err != nil {
+			return nil, err
+		}
+		id := string(header[0:4])
+		chunkSize := int64(binary.LittleEndian.Uint32(header[4:8]))
+		body := chunkStart + 8
+
+		switch id {
+		case "fmt ":
+			if chunkSize < 16 {
+				return nil, ErrMalformed
+			}
+			fmtChunk := make([]byte, 16)
+			if err := readAt(r, fmtChunk, body); err != nil {
+				return nil, err
+			}
+			info.Channels = int(binary.LittleEndian.Uint16(fmtChunk[2:4]))
+			info.SampleRate = int(binary.LittleEndian.Uint32(fmtChunk[4:8]))
+			byteRate = int64(binary.LittleEndian.Uint32(fmtChunk[8:12]))
+			fmtFound = true
+		case "data":
+			// streamed files may have unknown (or wrong) data size
+			dataSize = chunkSize
+			if body+dataSize > size {
+				dataSize = size - body
+			}
+			dataFound = true
+		}
+
+		// chunks are word aligned
+		chunkStart = body + chunkSize + chunkSize%2
+	}
+
+	if !fmtFound || !dataFound || byteRate == 0 {
+		return nil, ErrMalformed
+	}
+
+	info.Bitrate = int(byteRate * 8)
+	info.Duration = time.Duration(dataSize * int64(time.Second) / byteRate)
+
+	return info, nil
+}
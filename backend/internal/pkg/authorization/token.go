@@ -6,16 +6,20 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// CreateAuthToken creates access token of user session
 func CreateAuthToken(
-	userRole int, userID, userEmail, secret string,
+	userRole int, userID, userEmail, sessionID, secret string, ttl time.Duration,
 ) (string, error) {
+	now := time.Now()
 	claims := jwt.MapClaims{
 		"id":    userID,
 		"role":  userRole,
 		"email": userEmail,
+		"sid":   sessionID,
 
-		// token expires in 30 days
-		"exp": time.Now().Add(time.Hour * 24 * 30).Unix(),
+		// issue time is compared with revocation time of user tokens
+		"iat": now.Unix(),
+		"exp": now.Add(ttl).Unix(),
 	}
 
 	token := jwt.NewWithClaims(
@@ -19,3 +19,15 @@ func GetAudioContentTypeByExtension(ext string) string {
 		return "audio/mpeg"
 	}
 }
+
+// GetHLSContentTypeByExtension returns MIME type of hls playlist or segment
+func GetHLSContentTypeByExtension(ext string) string {
+	switch ext {
+	case ".m3u8":
+		return "application/vnd.apple.mpegurl"
+	case ".ts":
+		return "video/mp2t"
+	default:
+		return "application/octet-stream"
+	}
+}
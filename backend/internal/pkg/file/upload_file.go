@@ -1,13 +1,15 @@
 package uploadfile
 
 import (
+	"context"
 	"errors"
-	"io"
 	"log/slog"
+	"mime"
 	"mime/multipart"
-	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"tracker-backend/internal/pkg/storage"
 
 	"github.com/google/uuid"
 )
@@ -33,11 +35,17 @@ const (
 	BufferSize  = 32 * 1024 // 32KB
 )
 
-// UploadFile saves file on server and returns full path to file
+// public images are served by this url path prefix
+const PublicPathPrefix = "public/"
+
+// UploadFile saves file to blob store under dir with unique name
+// and returns blob key
 func UploadFile(
+	ctx context.Context,
+	store storage.BlobStore,
 	fileHeader *multipart.FileHeader,
 	file *multipart.File,
-	uploadDir string, // full upload path
+	dir string, // blob key prefix, e.g. "covers"
 	allowedExt map[string]bool, // allowed extensions
 ) (string, error) {
 	// configure logger
@@ -54,15 +62,8 @@ func UploadFile(
 		return "", ErrInvalidFileType
 	}
 
-	// create folder if it not exists
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		logger.Warn("failed to create new directory", slog.String("error", err.Error()))
-		return "", err
-	}
-
 	// create unique file name
-	newFileName := uuid.New().String() + ext
-	filePath := filepath.Join(uploadDir, newFileName)
+	key := path.Join(dir, uuid.New().String()+ext)
 
 	// open file
 	src, err := fileHeader.Open()
@@ -72,18 +73,40 @@ func UploadFile(
 	}
 	defer src.Close() // close file after saving
 
-	// create file on server
-	dst, err := os.Create(filePath)
-	if err != nil {
-		logger.Warn("failed to create new file", slog.String("error", err.Error()))
-		return "", err
+	contentType := mime.TypeByExtension(ext)
+	if contentType == "" {
+		contentType = fileHeader.Header.Get("Content-Type")
 	}
-	defer dst.Close()
 
-	if _, err := io.Copy(dst, src); err != nil {
-		logger.Warn("failed to copy data to created file", slog.String("error", err.Error()))
+	if err := store.Put(ctx, key, src, fileHeader.Size, contentType); err != nil {
+		logger.Warn("failed to save file", slog.String("error", err.Error()))
 		return "", err
 	}
 
-	return filePath, nil
+	return key, nil
+}
+
+// PublicPath returns url path of public image blob
+func PublicPath(key string) string {
+	return PublicPathPrefix + key
+}
+
+// UploadedKey returns blob key of public path of file uploaded to dir
+// by UploadFile. Paths outside of dir and shared default files are rejected,
+// so paths stored in documents can't point deletion to other files
+func UploadedKey(p, dir string) (string, bool) {
+	key, ok := strings.CutPrefix(p, PublicPathPrefix)
+	if !ok {
+		return "", false
+	}
+
+	keyDir, name := path.Split(key)
+	if keyDir != dir+"/" {
+		return "", false
+	}
+	// uploaded files have uuid names
+	if uuid.Validate(strings.TrimSuffix(name, path.Ext(name))) != nil {
+		return "", false
+	}
+	return key, true
 }
@@ -0,0 +1,147 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const lockKeyPrefix = "jobs:lock:"
+
+// Job is a task executed periodically by one of app instances
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Timeout  time.Duration
+	Run      func(ctx context.Context) error
+	// parallel job runs on every instance without lock,
+	// it must be safe for concurrent runs itself
+	Parallel bool
+}
+
+// Locker is a distributed lock shared by app instances
+type Locker interface {
+	AcquireLock(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	ReleaseLock(ctx context.Context, key, token string) error
+}
+
+// Runner runs jobs by schedule in background
+type Runner struct {
+	locker Locker
+	jobs   []Job
+
+	wg         sync.WaitGroup
+	stop       chan struct{}
+	runCtx     context.Context
+	cancelRuns context.CancelFunc
+}
+
+func NewRunner(locker Locker) *Runner {
+	return &Runner{
+		locker: locker,
+		stop:   make(chan struct{}),
+	}
+}
+
+// Add registers job, jobs must be added before Start
+func (r *Runner) Add(job Job) {
+	r.jobs = append(r.jobs, job)
+}
+
+// Start runs every job immediately and then by its interval
+func (r *Runner) Start() {
+	r.runCtx, r.cancelRuns = context.WithCancel(context.Background())
+
+	for _, job := range r.jobs {
+		r.wg.Add(1)
+		go r.loop(job)
+	}
+}
+
+// Stop stops scheduling and waits for running jobs.
+// Jobs still running when ctx is done are canceled
+func (r *Runner) Stop(ctx context.Context) error {
+	close(r.stop)
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		r.cancelRuns()
+		return nil
+	case <-ctx.Done():
+		r.cancelRuns()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (r *Runner) loop(job Job) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.runOnce(job)
+
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce runs job if no other instance has run it in current interval
+func (r *Runner) runOnce(job Job) {
+	// configure logger
+	logger := slog.With(
+		slog.String("function", "jobs.Runner.runOnce"),
+		slog.String("job", job.Name),
+	)
+
+	// lock is kept till the end of interval,
+	// so job runs once per interval across instances
+	key := lockKeyPrefix + job.Name
+	token := uuid.NewString()
+	if !job.Parallel {
+		acquired, err := r.locker.AcquireLock(r.runCtx, key, token, max(job.Interval, job.Timeout))
+		if err != nil {
+			logger.Warn("failed to acquire lock", slog.String("error", err.Error()))
+			return
+		}
+		if !acquired {
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.runCtx, job.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := job.Run(ctx); err != nil {
+		logger.Warn("job failed",
+			slog.Duration("duration", time.Since(start)),
+			slog.String("error", err.Error()),
+		)
+
+		// let any instance retry on next tick
+		if job.Parallel {
+			return
+		}
+		if err := r.locker.ReleaseLock(context.Background(), key, token); err != nil {
+			logger.Warn("failed to release lock", slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	logger.Info("job finished", slog.Duration("duration", time.Since(start)))
+}
@@ -0,0 +1,59 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+type SMTPOptions struct {
+	Host string
+	Port string
+	// auth is skipped if username is empty, e.g. for local smtp stand-in
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPMailer sends emails by smtp server, STARTTLS is used if server supports it
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPMailer(opts SMTPOptions) (*SMTPMailer, error) {
+	if opts.Host == "" || opts.Port == "" {
+		return nil, errors.New("smtp host and port are required")
+	}
+	if opts.From == "" {
+		return nil, errors.New("mail sender is required")
+	}
+
+	var auth smtp.Auth
+	if opts.Username != "" {
+		auth = smtp.PlainAuth("", opts.Username, opts.Password, opts.Host)
+	}
+
+	return &SMTPMailer{
+		addr: net.JoinHostPort(opts.Host, opts.Port),
+		auth: auth,
+		from: opts.From,
+	}, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	// headers must not be injected by recipient address
+	if strings.ContainsAny(msg.To, "\r\n") {
+		return errors.New("invalid recipient")
+	}
+
+	// smtp package doesn't support context, check it before sending at least
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, msg.build(m.from))
+}
@@ -0,0 +1,36 @@
+package pagination
+
+import (
+	"net/http"
+	"strconv"
+)
+
+const (
+	DefaultLimit int64 = 20
+	MaxLimit     int64 = 100
+)
+
+type Params struct {
+	Page  int64 `json:"page"`
+	Limit int64 `json:"limit"`
+}
+
+// FromRequest reads 'page' and 'limit' query params
+// invalid or missing values fall back to defaults
+func FromRequest(r *http.Request) Params {
+	p := Params{Page: 1, Limit: DefaultLimit}
+
+	if page, err := strconv.ParseInt(r.URL.Query().Get("page"), 10, 64); err == nil && page > 0 {
+		p.Page = page
+	}
+	if limit, err := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64); err == nil && limit > 0 {
+		p.Limit = min(limit, MaxLimit)
+	}
+
+	return p
+}
+
+// Skip returns count of documents to skip
+func (p Params) Skip() int64 {
+	return (p.Page - 1) * p.Limit
+}
@@ -0,0 +1,87 @@
+package signedurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrMissingSignature = errors.New("missing url signature")
+	ErrInvalidSignature = errors.New("invalid url signature")
+	ErrExpired          = errors.New("url expired")
+)
+
+// query params of signed url
+const (
+	ExpiresParam   = "exp"
+	UserIDParam    = "uid"
+	UserRoleParam  = "role"
+	SignatureParam = "sig"
+)
+
+// Signer is an identity the url is signed for, empty for anonymous
+type Signer struct {
+	UserID   string
+	UserRole int
+}
+
+// Sign returns query params granting access to resource till expiresAt
+func Sign(secret, resource string, signer Signer, expiresAt time.Time) url.Values {
+	exp := strconv.FormatInt(expiresAt.Unix(), 10)
+	role := "0"
+
+	query := url.Values{}
+	query.Set(ExpiresParam, exp)
+	if signer.UserID != "" {
+		role = strconv.Itoa(signer.UserRole)
+		query.Set(UserIDParam, signer.UserID)
+		query.Set(UserRoleParam, role)
+	}
+	query.Set(SignatureParam, signature(secret, resource, signer.UserID, role, exp))
+	return query
+}
+
+// Verify checks signature and expiry of query params,
+// returns identity the url is signed for
+func Verify(secret, resource string, query url.Values) (*Signer, error) {
+	exp, sig := query.Get(ExpiresParam), query.Get(SignatureParam)
+	if exp == "" || sig == "" {
+		return nil, ErrMissingSignature
+	}
+
+	userID := query.Get(UserIDParam)
+	role := "0"
+	if userID != "" {
+		role = query.Get(UserRoleParam)
+	}
+	expected := signature(secret, resource, userID, role, exp)
+	if !hmac.Equal([]byte(sig), []byte(expected)) {
+		return nil, ErrInvalidSignature
+	}
+
+	expiresAt, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	if time.Now().Unix() > expiresAt {
+		return nil, ErrExpired
+	}
+
+	userRole, err := strconv.Atoi(role)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	return &Signer{UserID: userID, UserRole: userRole}, nil
+}
+
+// signature is hmac-sha256 of newline separated fields
+func signature(secret, resource, userID, role, exp string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(resource + "\n" + userID + "\n" + role + "\n" + exp))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,106 @@
+package signedurl
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	const (
+		secret   = "test-secret"
+		resource = "track:1"
+	)
+	user := Signer{UserID: "user-1", UserRole: 2}
+	future := time.Now().Add(time.Hour)
+
+	// with returns copy of signed query with changed params
+	with := func(query url.Values, params map[string]string) url.Values {
+		changed := url.Values{}
+		for k, v := range query {
+			changed[k] = v
+		}
+		for k, v := range params {
+			if v == "" {
+				changed.Del(k)
+			} else {
+				changed.Set(k, v)
+			}
+		}
+		return changed
+	}
+	signed := Sign(secret, resource, user, future)
+	anonymous := Sign(secret, resource, Signer{}, future)
+
+	tests := []struct {
+		name     string
+		secret   string
+		resource string
+		query    url.Values
+		want     *Signer
+		err      error
+	}{
+		{name: "user", query: signed, want: &user},
+		{name: "anonymous", query: anonymous, want: &Signer{}},
+		{name: "anonymous role is ignored", query: with(anonymous, map[string]string{UserRoleParam: "3"}), want: &Signer{}},
+		{name: "no params", query: url.Values{}, err: ErrMissingSignature},
+		{name: "no signature", query: with(signed, map[string]string{SignatureParam: ""}), err: ErrMissingSignature},
+		{name: "no expiry", query: with(signed, map[string]string{ExpiresParam: ""}), err: ErrMissingSignature},
+		{name: "another resource", resource: "track:2", query: signed, err: ErrInvalidSignature},
+		{name: "another secret", secret: "other-secret", query: signed, err: ErrInvalidSignature},
+		{name: "changed user", query: with(signed, map[string]string{UserIDParam: "user-2"}), err: ErrInvalidSignature},
+		{name: "raised role", query: with(signed, map[string]string{UserRoleParam: "3"}), err: ErrInvalidSignature},
+		{name: "removed user", query: with(signed, map[string]string{UserIDParam: "", UserRoleParam: ""}), err: ErrInvalidSignature},
+		{name: "added user", query: with(anonymous, map[string]string{UserIDParam: "user-1"}), err: ErrInvalidSignature},
+		{
+			name:  "prolonged expiry",
+			query: with(signed, map[string]string{ExpiresParam: strconv.FormatInt(future.Add(time.Hour).Unix(), 10)}),
+			err:   ErrInvalidSignature,
+		},
+		{name: "forged signature", query: with(signed, map[string]string{SignatureParam: "forged"}), err: ErrInvalidSignature},
+		{name: "expired", query: Sign(secret, resource, user, time.Now().Add(-time.Second)), err: ErrExpired},
+		{
+			name:  "signed invalid expiry",
+			query: url.Values{ExpiresParam: {"soon"}, SignatureParam: {signature(secret, resource, "", "0", "soon")}},
+			err:   ErrInvalidSignature,
+		},
+		{
+			name: "signed invalid role",
+			query: url.Values{
+				ExpiresParam:   {strconv.FormatInt(future.Unix(), 10)},
+				UserIDParam:    {"user-1"},
+				UserRoleParam:  {"admin"},
+				SignatureParam: {signature(secret, resource, "user-1", "admin", strconv.FormatInt(future.Unix(), 10))},
+			},
+			err: ErrInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifySecret, verifyResource := secret, resource
+			if tt.secret != "" {
+				verifySecret = tt.secret
+			}
+			if tt.resource != "" {
+				verifyResource = tt.resource
+			}
+
+			got, err := Verify(verifySecret, verifyResource, tt.query)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Verify error = %v, want %v", err, tt.err)
+			}
+			if tt.want == nil {
+				if got != nil {
+					t.Fatalf("Verify = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil || *got != *tt.want {
+				t.Fatalf("Verify = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
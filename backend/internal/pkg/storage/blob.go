@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrBlobNotFound   = errors.New("blob not found")
+	ErrInvalidBlobKey = errors.New("invalid blob key")
+)
+
+// BlobStore stores media files by slash separated keys like "audio/<id>.mp3"
+type BlobStore interface {
+	// Put writes blob, existing blob is replaced
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open returns blob content, caller must close it
+	Open(ctx context.Context, key string) (Blob, error)
+	Stat(ctx context.Context, key string) (BlobInfo, error)
+	// Delete removes blob, missing blob is not an error
+	Delete(ctx context.Context, key string) error
+	// Move renames blob
+	Move(ctx context.Context, src, dst string) error
+	// List returns blobs with key prefix
+	List(ctx context.Context, prefix string) ([]BlobInfo, error)
+}
+
+// Blob is an opened blob content
+type Blob interface {
+	io.ReadSeekCloser
+	io.ReaderAt
+	Info() BlobInfo
+}
+
+type BlobInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// cleanKey validates key, keys can't leave store root
+func cleanKey(key string) (string, error) {
+	cleaned := path.Clean("/" + key)[1:]
+	if cleaned == "" || cleaned != strings.TrimPrefix(key, "/") {
+		return "", ErrInvalidBlobKey
+	}
+	return cleaned, nil
+}
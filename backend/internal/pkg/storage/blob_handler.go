@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// BlobHandler serves blobs with key prefix,
+// request path is a key relative to prefix
+func BlobHandler(store BlobStore, prefix string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// cleaned key must stay inside prefix
+		key := path.Join(prefix, r.URL.Path)
+		if !strings.HasPrefix(key, prefix+"/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		blob, err := store.Open(r.Context(), key)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer blob.Close()
+
+		info := blob.Info()
+		http.ServeContent(w, r, info.Key, info.ModTime, blob)
+	})
+}
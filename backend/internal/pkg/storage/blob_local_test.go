@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newTestLocalStore returns store in temp dir with blobs of keys,
+// content of blob is its key
+func newTestLocalStore(t *testing.T, keys ...string) *LocalBlobStore {
+	t.Helper()
+
+	store := NewLocalBlobStore(t.TempDir())
+	for _, key := range keys {
+		if err := store.Put(context.Background(), key, strings.NewReader(key), int64(len(key)), ""); err != nil {
+			t.Fatalf("Put(%q): %v", key, err)
+		}
+	}
+	return store
+}
+
+func readBlob(t *testing.T, store BlobStore, key string) string {
+	t.Helper()
+
+	blob, err := store.Open(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Open(%q): %v", key, err)
+	}
+	defer blob.Close()
+
+	data, err := io.ReadAll(blob)
+	if err != nil {
+		t.Fatalf("read %q: %v", key, err)
+	}
+	return string(data)
+}
+
+func TestLocalBlobStorePut(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		content string
+		err     error
+	}{
+		{name: "new blob", key: "audio/track.mp3", content: "audio"},
+		{name: "replaces blob", key: "covers/cover.jpg", content: "new cover"},
+		{name: "creates nested dirs", key: "hls/track/128k_000.ts", content: "segment"},
+		{name: "empty blob", key: "audio/empty.mp3", content: ""},
+		{name: "invalid key", key: "../outside", content: "x", err: ErrInvalidBlobKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestLocalStore(t, "covers/cover.jpg")
+
+			err := store.Put(context.Background(), tt.key, strings.NewReader(tt.content), int64(len(tt.content)), "")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Put error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			if got := readBlob(t, store, tt.key); got != tt.content {
+				t.Fatalf("content = %q, want %q", got, tt.content)
+			}
+
+			// temp files are renamed to blob
+			entries, err := os.ReadDir(filepath.Dir(filepath.Join(store.root, tt.key)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range entries {
+				if strings.Contains(e.Name(), ".tmp-") {
+					t.Fatalf("temp file %s is left", e.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestLocalBlobStoreOpen(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		size int64
+		err  error
+	}{
+		{name: "existing blob", key: "audio/track.mp3", size: int64(len("audio/track.mp3"))},
+		{name: "missing blob", key: "audio/missing.mp3", err: ErrBlobNotFound},
+		{name: "dir", key: "audio", err: ErrBlobNotFound},
+		{name: "invalid key", key: "audio/../../etc/passwd", err: ErrInvalidBlobKey},
+	}
+
+	store := newTestLocalStore(t, "audio/track.mp3")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blob, err := store.Open(context.Background(), tt.key)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Open error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+			defer blob.Close()
+
+			info := blob.Info()
+			if info.Key != tt.key || info.Size != tt.size {
+				t.Fatalf("info = %+v, want key %q size %d", info, tt.key, tt.size)
+			}
+
+			// blobs are seekable for range requests
+			if _, err := blob.Seek(6, io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+			rest, err := io.ReadAll(blob)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(rest) != tt.key[6:] {
+				t.Fatalf("content after seek = %q, want %q", rest, tt.key[6:])
+			}
+		})
+	}
+}
+
+func TestLocalBlobStoreList(t *testing.T) {
+	store := newTestLocalStore(t,
+		"audio/a.mp3",
+		"audio/b.mp3",
+		"covers/cover.jpg",
+		"hls/track/master.m3u8",
+		"hls/track/128k_000.ts",
+		"hls/track2/master.m3u8",
+	)
+
+	tests := []struct {
+		name   string
+		prefix string
+		want   []string
+		err    error
+	}{
+		{name: "dir prefix", prefix: "audio/", want: []string{"audio/a.mp3", "audio/b.mp3"}},
+		{name: "name prefix", prefix: "audio/a", want: []string{"audio/a.mp3"}},
+		{name: "nested dirs", prefix: "hls/", want: []string{
+			"hls/track/128k_000.ts", "hls/track/master.m3u8", "hls/track2/master.m3u8",
+		}},
+		{name: "dir name prefix", prefix: "hls/track/", want: []string{
+			"hls/track/128k_000.ts", "hls/track/master.m3u8",
+		}},
+		{name: "all blobs", prefix: "", want: []string{
+			"audio/a.mp3", "audio/b.mp3", "covers/cover.jpg",
+			"hls/track/128k_000.ts", "hls/track/master.m3u8", "hls/track2/master.m3u8",
+		}},
+		{name: "missing dir", prefix: "quarantine/", want: []string{}},
+		{name: "no matches", prefix: "audio/c", want: []string{}},
+		{name: "leaves root", prefix: "../", err: ErrInvalidBlobKey},
+		{name: "leaves prefix", prefix: "audio/../../", err: ErrInvalidBlobKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blobs, err := store.List(context.Background(), tt.prefix)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("List error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			got := make([]string, len(blobs))
+			for i, b := range blobs {
+				got[i] = b.Key
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("List(%q) = %v, want %v", tt.prefix, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalBlobStoreMove(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		dst  string
+		err  error
+	}{
+		{name: "to another dir", src: "audio/track.mp3", dst: "quarantine/audio/track.mp3"},
+		{name: "replaces blob", src: "audio/track.mp3", dst: "audio/other.mp3"},
+		{name: "missing blob", src: "audio/missing.mp3", dst: "quarantine/missing.mp3", err: ErrBlobNotFound},
+		{name: "invalid src", src: "../track.mp3", dst: "audio/track2.mp3", err: ErrInvalidBlobKey},
+		{name: "invalid dst", src: "audio/track.mp3", dst: "../track.mp3", err: ErrInvalidBlobKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestLocalStore(t, "audio/track.mp3", "audio/other.mp3")
+			ctx := context.Background()
+
+			err := store.Move(ctx, tt.src, tt.dst)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Move error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			if _, err := store.Stat(ctx, tt.src); !errors.Is(err, ErrBlobNotFound) {
+				t.Fatalf("Stat of moved blob error = %v, want %v", err, ErrBlobNotFound)
+			}
+			if got := readBlob(t, store, tt.dst); got != tt.src {
+				t.Fatalf("moved content = %q, want %q", got, tt.src)
+			}
+		})
+	}
+}
+
+func TestLocalBlobStoreDelete(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		err  error
+	}{
+		{name: "existing blob", key: "audio/track.mp3"},
+		{name: "missing blob", key: "audio/missing.mp3"},
+		{name: "invalid key", key: "../track.mp3", err: ErrInvalidBlobKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestLocalStore(t, "audio/track.mp3", "audio/other.mp3")
+			ctx := context.Background()
+
+			err := store.Delete(ctx, tt.key)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Delete error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			if _, err := store.Stat(ctx, tt.key); !errors.Is(err, ErrBlobNotFound) {
+				t.Fatalf("Stat of deleted blob error = %v, want %v", err, ErrBlobNotFound)
+			}
+			// other blobs are kept
+			if _, err := store.Stat(ctx, "audio/other.mp3"); err != nil {
+				t.Fatalf("Stat of other blob: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCleanKey(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		want string
+		err  error
+	}{
+		{name: "plain key", key: "audio/track.mp3", want: "audio/track.mp3"},
+		{name: "root key", key: "track.mp3", want: "track.mp3"},
+		{name: "leading slash", key: "/covers/cover.jpg", want: "covers/cover.jpg"},
+		{name: "nested key", key: "hls/track/128k_000.ts", want: "hls/track/128k_000.ts"},
+		{name: "empty", key: "", err: ErrInvalidBlobKey},
+		{name: "slash only", key: "/", err: ErrInvalidBlobKey},
+		{name: "dot", key: ".", err: ErrInvalidBlobKey},
+		{name: "parent dir", key: "..", err: ErrInvalidBlobKey},
+		{name: "leaves root", key: "../secret", err: ErrInvalidBlobKey},
+		{name: "leaves prefix", key: "covers/../audio/track.mp3", err: ErrInvalidBlobKey},
+		{name: "current dir segment", key: "covers/./cover.jpg", err: ErrInvalidBlobKey},
+		{name: "double slash", key: "covers//cover.jpg", err: ErrInvalidBlobKey},
+		{name: "trailing slash", key: "covers/", err: ErrInvalidBlobKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cleanKey(tt.key)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("cleanKey(%q) error = %v, want %v", tt.key, err, tt.err)
+			}
+			if got != tt.want {
+				t.Fatalf("cleanKey(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
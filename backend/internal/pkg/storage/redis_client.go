@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -89,7 +90,238 @@ func (r *RedisClient) GetJSONArray(
 }
 
 func (r *RedisClient) Delete(
+	ctx context.Context, keys ...string,
+) error {
+	return r.client.Del(ctx, keys...).Err()
+}
+
+// IncrHashFloat atomically increments float field of hash
+func (r *RedisClient) IncrHashFloat(
+	ctx context.Context, key, field string, incr float64,
+) error {
+	return r.client.HIncrByFloat(ctx, key, field, incr).Err()
+}
+
+// GetHashFloat returns all fields of hash parsed as floats
+// missing key results in empty map
+func (r *RedisClient) GetHashFloat(
+	ctx context.Context, key string,
+) (map[string]float64, error) {
+	data, err := r.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]float64, len(data))
+	for field, value := range data {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of field %s: %w", field, err)
+		}
+		res[field] = f
+	}
+	return res, nil
+}
+
+// GetString returns string value of key
+func (r *RedisClient) GetString(
+	ctx context.Context, key string,
+) (string, error) {
+	return r.client.Get(ctx, key).Result()
+}
+
+// SetStringNX sets value only if key doesn't exist,
+// returns true if value was set
+func (r *RedisClient) SetStringNX(
+	ctx context.Context,
+	key string, value string,
+	ttl time.Duration,
+) (bool, error) {
+	return r.client.SetNX(ctx, key, value, ttl).Result()
+}
+
+// IncrBy atomically increments integer value of key and updates its ttl
+func (r *RedisClient) IncrBy(
+	ctx context.Context,
+	key string, value int64,
+	ttl time.Duration,
+) (int64, error) {
+	pipe := r.client.TxPipeline()
+	incr := pipe.IncrBy(ctx, key, value)
+	pipe.Expire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// TTL returns remaining time to live of key, 0 if key is missing or persistent
+func (r *RedisClient) TTL(
 	ctx context.Context, key string,
+) (time.Duration, error) {
+	ttl, err := r.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	return max(ttl, 0), nil
+}
+
+// releaseLockScript deletes lock only if it's held by token owner
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// AcquireLock sets lock key with owner token if lock is free,
+// returns true if lock is acquired
+func (r *RedisClient) AcquireLock(
+	ctx context.Context,
+	key string, token string,
+	ttl time.Duration,
+) (bool, error) {
+	return r.client.SetNX(ctx, key, token, ttl).Result()
+}
+
+// ReleaseLock releases lock acquired with token
+func (r *RedisClient) ReleaseLock(
+	ctx context.Context, key string, token string,
+) error {
+	return releaseLockScript.Run(ctx, r.client, []string{key}, token).Err()
+}
+
+// GetStrings returns values of keys, missing keys have empty values
+func (r *RedisClient) GetStrings(
+	ctx context.Context, keys ...string,
+) ([]string, error) {
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, len(values))
+	for i, value := range values {
+		if str, ok := value.(string); ok {
+			res[i] = str
+		}
+	}
+	return res, nil
+}
+
+// CompareAndDelete atomically deletes key if its value matches,
+// returns false if current value differs
+func (r *RedisClient) CompareAndDelete(
+	ctx context.Context, key, value string,
+) (bool, error) {
+	deleted, err := releaseLockScript.Run(ctx, r.client, []string{key}, value).Int()
+	if err != nil {
+		return false, err
+	}
+	return deleted == 1, nil
+}
+
+// compareAndSwapScript replaces value only if current value matches
+var compareAndSwapScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+	return 1
+end
+return 0
+`)
+
+// CompareAndSwap atomically replaces old value of key with new one
+// and updates its ttl, returns false if current value differs
+func (r *RedisClient) CompareAndSwap(
+	ctx context.Context,
+	key string, old, new string,
+	ttl time.Duration,
+) (bool, error) {
+	swapped, err := compareAndSwapScript.Run(ctx, r.client, []string{key}, old, new, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return swapped == 1, nil
+}
+
+// AddToSet adds members to set and updates its ttl
+func (r *RedisClient) AddToSet(
+	ctx context.Context,
+	key string, ttl time.Duration, members ...string,
+) error {
+	pipe := r.client.TxPipeline()
+	pipe.SAdd(ctx, key, members)
+	pipe.Expire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// RemoveFromSet removes members from set
+func (r *RedisClient) RemoveFromSet(
+	ctx context.Context, key string, members ...string,
 ) error {
-	return r.client.Del(ctx, key).Err()
+	return r.client.SRem(ctx, key, members).Err()
+}
+
+// GetSet returns all members of set, missing key results in empty slice
+func (r *RedisClient) GetSet(
+	ctx context.Context, key string,
+) ([]string, error) {
+	return r.client.SMembers(ctx, key).Result()
+}
+
+// takeTokenScript implements token bucket as GCRA,
+// key keeps theoretical arrival time of the next request in ms,
+// redis time is used so app instances with skewed clocks share limits
+var takeTokenScript = redis.NewScript(`
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local interval = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+
+local tat = tonumber(redis.call("GET", KEYS[1]))
+if not tat or tat < now then
+	tat = now
+end
+
+local newTat = tat + interval
+local allowAt = newTat - limit * interval
+if allowAt > now then
+	return {0, 0, math.ceil(allowAt - now), math.ceil(tat - now)}
+end
+
+redis.call("SET", KEYS[1], newTat, "PX", math.ceil(newTat - now))
+return {1, math.floor((now - allowAt) / interval), 0, math.ceil(newTat - now)}
+`)
+
+// RateLimitResult is a result of taking rate limiter token
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int64
+	// time till the next request is allowed, 0 if request is allowed
+	RetryAfter time.Duration
+	// time till all tokens are refilled
+	ResetAfter time.Duration
+}
+
+// TakeToken takes token from bucket of key holding up to limit tokens,
+// bucket is refilled evenly, limit tokens per window
+func (r *RedisClient) TakeToken(
+	ctx context.Context,
+	key string, limit int64,
+	window time.Duration,
+) (RateLimitResult, error) {
+	interval := float64(window.Milliseconds()) / float64(limit)
+
+	res, err := takeTokenScript.Run(ctx, r.client, []string{key}, interval, limit).Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	return RateLimitResult{
+		Allowed:    res[0] == 1,
+		Remaining:  res[1],
+		RetryAfter: time.Duration(res[2]) * time.Millisecond,
+		ResetAfter: time.Duration(res[3]) * time.Millisecond,
+	}, nil
 }
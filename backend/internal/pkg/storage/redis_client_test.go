@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// testRedisAddrEnv is an address of redis used by tests,
+// tests using redis are skipped if it is not set
+const testRedisAddrEnv = "TEST_REDIS_ADDR"
+
+func newTestRedisClient(t *testing.T) *RedisClient {
+	t.Helper()
+
+	addr := os.Getenv(testRedisAddrEnv)
+	if addr == "" {
+		t.Skipf("%s is not set", testRedisAddrEnv)
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatalf("invalid %s: %v", testRedisAddrEnv, err)
+	}
+
+	client, err := NewRedisClient(context.Background(), host, port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestTakeToken(t *testing.T) {
+	redis := newTestRedisClient(t)
+
+	type take struct {
+		allowed   bool
+		remaining int64
+	}
+	tests := []struct {
+		name   string
+		limit  int64
+		window time.Duration
+		takes  []take
+	}{
+		{
+			name:   "burst up to limit",
+			limit:  3,
+			window: time.Hour,
+			takes: []take{
+				{allowed: true, remaining: 2},
+				{allowed: true, remaining: 1},
+				{allowed: true, remaining: 0},
+				{allowed: false, remaining: 0},
+				{allowed: false, remaining: 0},
+			},
+		},
+		{
+			name:   "single token",
+			limit:  1,
+			window: time.Hour,
+			takes: []take{
+				{allowed: true, remaining: 0},
+				{allowed: false, remaining: 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			key := "test:rate_limit:" + uuid.NewString()
+			t.Cleanup(func() { redis.Delete(ctx, key) })
+
+			interval := tt.window / time.Duration(tt.limit)
+			for i, want := range tt.takes {
+				res, err := redis.TakeToken(ctx, key, tt.limit, tt.window)
+				if err != nil {
+					t.Fatalf("take %d: %v", i, err)
+				}
+				if res.Allowed != want.allowed || res.Remaining != want.remaining {
+					t.Fatalf("take %d = allowed %v remaining %d, want allowed %v remaining %d",
+						i, res.Allowed, res.Remaining, want.allowed, want.remaining)
+				}
+
+				if res.Allowed && res.RetryAfter != 0 {
+					t.Fatalf("take %d: allowed with retry after %s", i, res.RetryAfter)
+				}
+				// the next token is refilled after one interval
+				if !res.Allowed && (res.RetryAfter <= 0 || res.RetryAfter > interval) {
+					t.Fatalf("take %d: retry after %s, want in (0, %s]", i, res.RetryAfter, interval)
+				}
+				if res.ResetAfter <= 0 || res.ResetAfter > tt.window {
+					t.Fatalf("take %d: reset after %s, want in (0, %s]", i, res.ResetAfter, tt.window)
+				}
+			}
+		})
+	}
+}
+
+func TestTakeTokenRefill(t *testing.T) {
+	redis := newTestRedisClient(t)
+	ctx := context.Background()
+	key := "test:rate_limit:" + uuid.NewString()
+	t.Cleanup(func() { redis.Delete(ctx, key) })
+
+	// one token per 100ms
+	limit, window := int64(2), 200*time.Millisecond
+	for range limit {
+		if res, err := redis.TakeToken(ctx, key, limit, window); err != nil || !res.Allowed {
+			t.Fatalf("take = %+v, %v, want allowed", res, err)
+		}
+	}
+	res, err := redis.TakeToken(ctx, key, limit, window)
+	if err != nil || res.Allowed {
+		t.Fatalf("take over limit = %+v, %v, want denied", res, err)
+	}
+
+	time.Sleep(res.RetryAfter)
+	if res, err := redis.TakeToken(ctx, key, limit, window); err != nil || !res.Allowed {
+		t.Fatalf("take after retry = %+v, %v, want allowed", res, err)
+	}
+
+	// different keys have separate buckets
+	other := key + ":other"
+	t.Cleanup(func() { redis.Delete(ctx, other) })
+	if res, err := redis.TakeToken(ctx, other, limit, window); err != nil || res.Remaining != limit-1 {
+		t.Fatalf("take of other key = %+v, %v, want remaining %d", res, err, limit-1)
+	}
+}
@@ -3,8 +3,13 @@ package playlist
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"tracker-backend/internal/auth"
+	"tracker-backend/internal/auth/ownership"
+	"tracker-backend/internal/pkg/pagination"
 	"tracker-backend/internal/pkg/response"
 	"tracker-backend/internal/pkg/service"
+	playlistType "tracker-backend/internal/playlist/type"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -24,6 +29,8 @@ func NewPlaylistHandler(service *PlaylistService) *PlaylistHandler {
 	}
 }
 
+// PUT /playlist/{id}/tracks/{trackID}
+// deprecated: appends track without version check, use InsertTrack
 func (h *PlaylistHandler) AddTrack(w http.ResponseWriter, r *http.Request) {
 	playlistID := chi.URLParam(r, "id")
 	trackID := chi.URLParam(r, "trackID")
@@ -38,54 +45,326 @@ func (h *PlaylistHandler) AddTrack(w http.ResponseWriter, r *http.Request) {
 	// execute service function
 	updated, err := h.Service.PushTrackLink(r.Context(), playlistID, trackID)
 	if err != nil {
-		if errors.Is(err, service.ErrNotFound) {
-			render.Status(r, http.StatusNotFound)
-			render.JSON(w, r, response.Error("playlist not found"))
-			return
-		}
-		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, response.Error("failed to add track"))
+		renderTracksUpdateError(w, r, err)
 		return
 	}
 
-	render.JSON(w, r, updated)
+	render.JSON(w, r, updated.ToResponse())
 }
 
+// DELETE /playlist/{id}/tracks/{trackID}?version
 func (h *PlaylistHandler) RemoveTrack(w http.ResponseWriter, r *http.Request) {
-	playlistID := chi.URLParam(r, "id")
+	// get playlist loaded by ownership middleware
+	ctx := r.Context()
+	var playlist playlistType.Playlist
+	if err := ownership.ResourceFromContext(ctx, &playlist); err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error("failed to get playlist"))
+		return
+	}
 	trackID := chi.URLParam(r, "trackID")
 
-	// validate param
+	// validate params
 	if err := h.validator.Var(trackID, "required,uuid4"); err != nil {
 		render.Status(r, http.StatusBadRequest)
 		render.JSON(w, r, response.Error("invalid track ID"))
 		return
 	}
+	version, err := strconv.ParseInt(r.URL.Query().Get("version"), 10, 64)
+	if err != nil || version < 0 {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid version"))
+		return
+	}
+
+	// execute service function
+	updated, err := h.Service.RemoveTrackLink(ctx, &playlist, trackID, version)
+	if err != nil {
+		renderTracksUpdateError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, updated.ToResponse())
+}
+
+// POST /playlist/{id}/tracks
+func (h *PlaylistHandler) InsertTrack(w http.ResponseWriter, r *http.Request) {
+	playlistID := chi.URLParam(r, "id")
+
+	// decode json
+	var req playlistType.InsertTrackRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid request body"))
+		return
+	}
+
+	// validate request
+	if err := h.validator.Struct(req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.ValidationErrorsResp(err.(validator.ValidationErrors)))
+		return
+	}
+
+	// execute service function
+	updated, err := h.Service.InsertTrack(r.Context(), playlistID, &req)
+	if err != nil {
+		renderTracksUpdateError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, updated.ToResponse())
+}
+
+// PUT /playlist/{id}/tracks/move
+func (h *PlaylistHandler) MoveTrack(w http.ResponseWriter, r *http.Request) {
+	// get playlist loaded by ownership middleware
+	ctx := r.Context()
+	var playlist playlistType.Playlist
+	if err := ownership.ResourceFromContext(ctx, &playlist); err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error("failed to get playlist"))
+		return
+	}
+
+	// decode json
+	var req playlistType.MoveTrackRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid request body"))
+		return
+	}
+
+	// validate request
+	if err := h.validator.Struct(req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.ValidationErrorsResp(err.(validator.ValidationErrors)))
+		return
+	}
+
+	// execute service function
+	updated, err := h.Service.MoveTrack(ctx, &playlist, &req)
+	if err != nil {
+		renderTracksUpdateError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, updated.ToResponse())
+}
+
+// PUT /playlist/{id}/tracks
+func (h *PlaylistHandler) ReorderTracks(w http.ResponseWriter, r *http.Request) {
+	// get playlist loaded by ownership middleware
+	ctx := r.Context()
+	var playlist playlistType.Playlist
+	if err := ownership.ResourceFromContext(ctx, &playlist); err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error("failed to get playlist"))
+		return
+	}
+
+	// decode json
+	var req playlistType.ReorderTracksRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid request body"))
+		return
+	}
+
+	// validate request
+	if err := h.validator.Struct(req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.ValidationErrorsResp(err.(validator.ValidationErrors)))
+		return
+	}
+
+	// execute service function
+	updated, err := h.Service.ReorderTracks(ctx, &playlist, &req)
+	if err != nil {
+		renderTracksUpdateError(w, r, err)
+		return
+	}
+
+	render.JSON(w, r, updated.ToResponse())
+}
+
+// renderTracksUpdateError maps playlist tracks update error to response
+func renderTracksUpdateError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, response.Error("playlist not found"))
+		return
+	case errors.Is(err, ErrTrackNotFound), errors.Is(err, ErrTrackNotLinked):
+		render.Status(r, http.StatusNotFound)
+	case errors.Is(err, playlistType.ErrVersionConflict):
+		render.Status(r, http.StatusConflict)
+	case errors.Is(err, ErrInvalidPosition), errors.Is(err, ErrNotPermutation):
+		render.Status(r, http.StatusBadRequest)
+	default:
+		render.Status(r, http.StatusInternalServerError)
+	}
+	render.JSON(w, r, response.Error(err.Error()))
+}
+
+// POST /playlist
+func (h *PlaylistHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(string)
+
+	// decode json
+	var req playlistType.PlaylistCreateRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid request body"))
+		return
+	}
+	req.UserID = userID
+	req.IsDefault = false
+
+	// validate request
+	if err := h.validator.Struct(req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.ValidationErrorsResp(err.(validator.ValidationErrors)))
+		return
+	}
+
+	// execute service function
+	created, err := h.Service.Create(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, playlistType.ErrNameTaken) {
+			render.Status(r, http.StatusConflict)
+		} else {
+			render.Status(r, http.StatusInternalServerError)
+		}
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, created.ToResponse())
+}
+
+// PUT /playlist/{id}
+func (h *PlaylistHandler) Update(w http.ResponseWriter, r *http.Request) {
+	// get playlist loaded by ownership middleware
+	ctx := r.Context()
+	var playlist playlistType.Playlist
+	if err := ownership.ResourceFromContext(ctx, &playlist); err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error("failed to get playlist"))
+		return
+	}
+
+	// decode json
+	var req playlistType.UpdateRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid request body"))
+		return
+	}
+
+	// validate request
+	if err := h.validator.Struct(req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.ValidationErrorsResp(err.(validator.ValidationErrors)))
+		return
+	}
 
 	// execute service function
-	updated, err := h.Service.RemoveTrackLink(r.Context(), playlistID, trackID)
+	updated, err := h.Service.Update(ctx, &playlist, &req)
 	if err != nil {
-		if errors.Is(err, service.ErrNotFound) {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
 			render.Status(r, http.StatusNotFound)
-			render.JSON(w, r, response.Error("playlist not found"))
-			return
+		case errors.Is(err, playlistType.ErrNameTaken):
+			render.Status(r, http.StatusConflict)
+		case errors.Is(err, playlistType.ErrDefaultPlaylist):
+			render.Status(r, http.StatusForbidden)
+		case errors.Is(err, playlistType.ErrNothingToUpdate):
+			render.Status(r, http.StatusBadRequest)
+		default:
+			render.Status(r, http.StatusInternalServerError)
 		}
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
+
+	render.JSON(w, r, updated.ToResponse())
+}
+
+// DELETE /playlist/{id}
+func (h *PlaylistHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	// get playlist loaded by ownership middleware
+	ctx := r.Context()
+	var playlist playlistType.Playlist
+	if err := ownership.ResourceFromContext(ctx, &playlist); err != nil {
 		render.Status(r, http.StatusInternalServerError)
-		render.JSON(w, r, response.Error("failed to remove track"))
+		render.JSON(w, r, response.Error("failed to get playlist"))
+		return
+	}
+
+	// execute service function
+	if err := h.Service.Delete(ctx, &playlist); err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			render.Status(r, http.StatusNotFound)
+		case errors.Is(err, playlistType.ErrDefaultPlaylist):
+			render.Status(r, http.StatusForbidden)
+		default:
+			render.Status(r, http.StatusInternalServerError)
+		}
+		render.JSON(w, r, response.Error(err.Error()))
 		return
 	}
 
-	render.JSON(w, r, updated)
+	w.WriteHeader(http.StatusNoContent)
 }
 
+// GET /playlist?page&limit
 func (h *PlaylistHandler) MyPlaylists(w http.ResponseWriter, r *http.Request) {
-	// get user context key
-	// execute service function
-	// return result
+	userID := r.Context().Value(auth.UserIDKey).(string)
+	h.renderUserPlaylists(w, r, userID, false)
+}
+
+// GET /playlist/user/{userID}?page&limit
+func (h *PlaylistHandler) UserPlaylists(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+
+	// validate param
+	if err := h.validator.Var(userID, "required,uuid4"); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid user ID"))
+		return
+	}
+
+	// private playlists are listed to their owner only
+	onlyPublic := userID != r.Context().Value(auth.UserIDKey).(string)
+	h.renderUserPlaylists(w, r, userID, onlyPublic)
 }
 
-func (h *PlaylistHandler) Tracks(w http.ResponseWriter, r *http.Request) {
-	// get user context key
+// renderUserPlaylists renders page of user playlists
+func (h *PlaylistHandler) renderUserPlaylists(
+	w http.ResponseWriter, r *http.Request, userID string, onlyPublic bool,
+) {
 	// execute service function
-	// return result
+	params := pagination.FromRequest(r)
+	playlists, total, err := h.Service.GetByUser(r.Context(), userID, onlyPublic, params)
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
+
+	// execution result to response
+	res := playlistType.PlaylistListResponse{
+		Items: make([]playlistType.PlaylistResponse, len(playlists)),
+		Total: total,
+		Page:  params.Page,
+		Limit: params.Limit,
+	}
+	for i, p := range playlists {
+		res.Items[i] = p.ToResponse()
+	}
+
+	render.JSON(w, r, res)
 }
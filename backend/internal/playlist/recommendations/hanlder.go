@@ -1 +1,97 @@
 package playlistRecommendations
+
+import (
+	"errors"
+	"net/http"
+	"tracker-backend/internal/auth"
+	"tracker-backend/internal/auth/ownership"
+	"tracker-backend/internal/pkg/response"
+	"tracker-backend/internal/pkg/service"
+	playlistType "tracker-backend/internal/playlist/type"
+
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type PlaylistRecommendationsHandler struct {
+	Service   *PlaylistRecommendationsService
+	Validator *validator.Validate
+}
+
+func NewPlaylistRecommendationsHandler(s *PlaylistRecommendationsService) *PlaylistRecommendationsHandler {
+	return &PlaylistRecommendationsHandler{
+		Service:   s,
+		Validator: validator.New(),
+	}
+}
+
+// POST /playlist/recommendations
+func (h *PlaylistRecommendationsHandler) Generate(w http.ResponseWriter, r *http.Request) {
+	// get context keys
+	ctx := r.Context()
+	userID := ctx.Value(auth.UserIDKey).(string)
+
+	// decode json
+	var req playlistType.GenerateRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid request body"))
+		return
+	}
+
+	// validate request
+	if err := h.Validator.Struct(req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.ValidationErrorsResp(err.(validator.ValidationErrors)))
+		return
+	}
+
+	// execute service function
+	playlist, err := h.Service.Generate(ctx, userID, &req)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	// send response
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, playlist.ToResponse())
+}
+
+// POST /playlist/{id}/recommendations/refresh
+func (h *PlaylistRecommendationsHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	// get playlist loaded by ownership middleware
+	ctx := r.Context()
+	var playlist playlistType.Playlist
+	if err := ownership.ResourceFromContext(ctx, &playlist); err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error("failed to get playlist"))
+		return
+	}
+
+	// execute service function
+	updated, err := h.Service.Refresh(ctx, &playlist)
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	// send response
+	render.JSON(w, r, updated.ToResponse())
+}
+
+// renderError maps playlist generation error to response
+func renderError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound), errors.Is(err, ErrNoSimilarTracks):
+		render.Status(r, http.StatusNotFound)
+	case errors.Is(err, ErrInvalidGenre), errors.Is(err, ErrInvalidSeedType),
+		errors.Is(err, ErrSeedHasNoGenres), errors.Is(err, ErrNotGenerated):
+		render.Status(r, http.StatusBadRequest)
+	case errors.Is(err, playlistType.ErrVersionConflict), errors.Is(err, playlistType.ErrNameTaken):
+		render.Status(r, http.StatusConflict)
+	default:
+		render.Status(r, http.StatusInternalServerError)
+	}
+	render.JSON(w, r, response.Error(err.Error()))
+}
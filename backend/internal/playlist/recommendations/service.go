@@ -1 +1,404 @@
 package playlistRecommendations
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	albumType "tracker-backend/internal/album/type"
+	artistType "tracker-backend/internal/artist/type"
+	"tracker-backend/internal/genre"
+	genreType "tracker-backend/internal/genre/type"
+	"tracker-backend/internal/pkg/service"
+	playlistType "tracker-backend/internal/playlist/type"
+	"tracker-backend/internal/track"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const (
+	defaultLimit = 30
+	// max number of co-occurring tracks passed to candidates aggregation
+	coOccurrenceLimit = 200
+	// score weights of genre overlap and co-occurrence in public playlists
+	genreWeight        = 0.6
+	coOccurrenceWeight = 0.4
+)
+
+var (
+	ErrInvalidGenre    = errors.New("invalid genre")
+	ErrNoSimilarTracks = errors.New("no similar tracks found")
+	ErrNotGenerated    = errors.New("playlist is not generated")
+	ErrInvalidSeedType = errors.New("invalid seed type")
+	ErrSeedHasNoGenres = errors.New("seed has no genres")
+)
+
+type PlaylistWriter interface {
+	Create(ctx context.Context, req playlistType.PlaylistCreateRequest) (*playlistType.Playlist, error)
+	SetTrackIDs(ctx context.Context, playlistID string, version int64, trackIDs []string) (*playlistType.Playlist, error)
+}
+
+type PlaylistRecommendationsService struct {
+	tracksCol      *mongo.Collection
+	albumsCol      *mongo.Collection
+	artistsCol     *mongo.Collection
+	playlistsCol   *mongo.Collection
+	playlistWriter PlaylistWriter
+}
+
+func NewPlaylistRecommendationsService(
+	tracksCol, albumsCol, artistsCol, playlistsCol *mongo.Collection,
+	playlistWriter PlaylistWriter,
+) *PlaylistRecommendationsService {
+	return &PlaylistRecommendationsService{
+		tracksCol:      tracksCol,
+		albumsCol:      albumsCol,
+		artistsCol:     artistsCol,
+		playlistsCol:   playlistsCol,
+		playlistWriter: playlistWriter,
+	}
+}
+
+// resolvedSeed contains data used to pick similar tracks
+type resolvedSeed struct {
+	name     string
+	genres   []string
+	trackIDs []string
+	// seed track is placed first in generated playlist
+	leadTrackID string
+}
+
+// weightedTrack is a co-occurrence vector element passed to aggregation
+type weightedTrack struct {
+	TrackID string  `bson:"k"`
+	Weight  float64 `bson:"v"`
+}
+
+// Generate creates playlist of tracks similar to seed
+func (s *PlaylistRecommendationsService) Generate(
+	ctx context.Context, userID string, req *playlistType.GenerateRequest,
+) (*playlistType.Playlist, error) {
+	// configure logger
+	logger := slog.With(slog.String("function", "playlistRecommendations.PlaylistRecommendationsService.Generate"))
+
+	seed := &playlistType.Seed{
+		Type:  req.SeedType,
+		Value: req.Seed,
+		Limit: req.Limit,
+	}
+	if seed.Limit == 0 {
+		seed.Limit = defaultLimit
+	}
+	if seed.Type == playlistType.SeedGenre {
+		seed.Value = strings.ToLower(strings.TrimSpace(seed.Value))
+	}
+
+	resolved, err := s.resolveSeed(ctx, userID, seed)
+	if err != nil {
+		return nil, err
+	}
+
+	trackIDs, err := s.pickTracks(ctx, userID, resolved, seed.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	name := req.Name
+	if name == "" {
+		name = resolved.name + " Radio"
+	}
+
+	playlist, err := s.playlistWriter.Create(ctx, playlistType.PlaylistCreateRequest{
+		Name:     name,
+		UserID:   userID,
+		IsPublic: req.IsPublic,
+		TrackIDs: trackIDs,
+		Seed:     seed,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("playlist generated",
+		slog.Group("info",
+			slog.String("userID", userID),
+			slog.String("id", playlist.ID),
+			slog.String("seedType", seed.Type),
+			slog.String("seed", seed.Value),
+			slog.Int("tracks", len(trackIDs)),
+		),
+	)
+
+	return playlist, nil
+}
+
+// Refresh picks tracks of generated playlist again
+// playlist is loaded by ownership middleware
+func (s *PlaylistRecommendationsService) Refresh(
+	ctx context.Context, playlist *playlistType.Playlist,
+) (*playlistType.Playlist, error) {
+	if playlist.Seed == nil {
+		return nil, ErrNotGenerated
+	}
+
+	resolved, err := s.resolveSeed(ctx, playlist.UserID, playlist.Seed)
+	if err != nil {
+		return nil, err
+	}
+
+	trackIDs, err := s.pickTracks(ctx, playlist.UserID, resolved, playlist.Seed.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.playlistWriter.SetTrackIDs(ctx, playlist.ID, playlist.Version, trackIDs)
+}
+
+// resolveSeed finds seed genres and tracks.
+// Only albums visible to user are used, seed of hidden album is not found
+func (s *PlaylistRecommendationsService) resolveSeed(
+	ctx context.Context, userID string, seed *playlistType.Seed,
+) (*resolvedSeed, error) {
+	var (
+		res = &resolvedSeed{}
+		err error
+	)
+
+	if seed.Type == playlistType.SeedGenre {
+		if !slices.Contains(genreType.AllowedGenres, seed.Value) {
+			return nil, ErrInvalidGenre
+		}
+		res.name = seed.Value
+		res.genres = []string{seed.Value}
+		return res, nil
+	}
+
+	ownArtistIDs, err := artistType.OwnArtistIDs(ctx, s.artistsCol, userID)
+	if err != nil {
+		return nil, errors.New("failed to resolve seed")
+	}
+	visible := func(filter bson.M) bson.M {
+		return bson.M{"$and": bson.A{filter, albumType.VisibilityFilter("", ownArtistIDs)}}
+	}
+
+	switch seed.Type {
+	case playlistType.SeedTrack:
+		var t track.Track
+		if err := findOne(ctx, s.tracksCol, bson.M{"id": seed.Value}, &t); err != nil {
+			return nil, err
+		}
+		var a albumType.Album
+		if err := findOne(ctx, s.albumsCol, visible(bson.M{"id": t.AlbumID}), &a); err != nil {
+			return nil, err
+		}
+		res.name = t.Title
+		res.genres = t.Genre
+		res.trackIDs = []string{t.ID}
+		res.leadTrackID = t.ID
+
+	case playlistType.SeedAlbum:
+		var a albumType.Album
+		if err := findOne(ctx, s.albumsCol, visible(bson.M{"id": seed.Value}), &a); err != nil {
+			return nil, err
+		}
+		res.name = a.Title
+		res.genres = a.Genres
+		res.trackIDs, err = s.distinct(ctx, s.tracksCol, "id", bson.M{"album": a.ID})
+
+	case playlistType.SeedArtist:
+		var a artistType.Artist
+		if err := findOne(ctx, s.artistsCol, bson.M{"id": seed.Value}, &a); err != nil {
+			return nil, err
+		}
+		res.name = a.Name
+		var albumIDs []string
+		albumIDs, err = s.distinct(ctx, s.albumsCol, "id", visible(bson.M{"artistID": a.ID}))
+		if err != nil {
+			break
+		}
+		res.genres, err = s.distinct(ctx, s.albumsCol, "genres", visible(bson.M{"artistID": a.ID}))
+		if err != nil {
+			break
+		}
+		res.trackIDs, err = s.distinct(ctx, s.tracksCol, "id", bson.M{"album": bson.M{"$in": albumIDs}})
+
+	default:
+		return nil, ErrInvalidSeedType
+	}
+	if err != nil {
+		return nil, errors.New("failed to resolve seed")
+	}
+
+	res.genres = genre.NormalizeGenres(res.genres)
+	if len(res.genres) == 0 {
+		return nil, ErrSeedHasNoGenres
+	}
+
+	return res, nil
+}
+
+// pickTracks returns ids of public tracks similar to seed.
+// Tracks are scored by genre overlap (Jaccard index) with seed
+// and by co-occurrence with seed tracks in public playlists of other users
+func (s *PlaylistRecommendationsService) pickTracks(
+	ctx context.Context, userID string, seed *resolvedSeed, limit int,
+) ([]string, error) {
+	// configure logger
+	logger := slog.With(slog.String("function", "playlistRecommendations.PlaylistRecommendationsService.pickTracks"))
+
+	coOccurrence, err := s.getCoOccurrence(ctx, userID, seed.trackIDs)
+	if err != nil {
+		logger.Warn("failed to count co-occurrence", slog.String("error", err.Error()))
+		return nil, errors.New("failed to pick tracks")
+	}
+	coOccurringIDs := make([]string, len(coOccurrence))
+	for i, t := range coOccurrence {
+		coOccurringIDs[i] = t.TrackID
+	}
+
+	// seed tracks are not recommended except lead track
+	excludedIDs := slices.DeleteFunc(slices.Clone(seed.trackIDs), func(id string) bool {
+		return id == seed.leadTrackID
+	})
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"id": bson.M{"$nin": excludedIDs}}},
+		{"$addFields": bson.M{
+			"_genres": bson.M{"$map": bson.M{
+				"input": bson.M{"$ifNull": bson.A{"$genre", bson.A{}}},
+				"in":    bson.M{"$toLower": bson.M{"$trim": bson.M{"input": "$$this"}}},
+			}},
+		}},
+		{"$match": bson.M{"$or": bson.A{
+			bson.M{"_genres": bson.M{"$in": seed.genres}},
+			bson.M{"id": bson.M{"$in": coOccurringIDs}},
+		}}},
+		// public tracks only
+		{"$lookup": bson.M{
+			"from":         "albums",
+			"localField":   "album",
+			"foreignField": "id",
+			"as":           "_album",
+		}},
+		{"$match": albumType.VisibilityFilter("_album.", nil)},
+		{"$addFields": bson.M{
+			"_genreScore": bson.M{"$divide": bson.A{
+				bson.M{"$size": bson.M{"$setIntersection": bson.A{"$_genres", seed.genres}}},
+				bson.M{"$size": bson.M{"$setUnion": bson.A{"$_genres", seed.genres}}},
+			}},
+			"_coOccurrenceScore": bson.M{"$let": bson.M{
+				"vars": bson.M{"m": bson.M{"$first": bson.M{"$filter": bson.M{
+					"input": coOccurrence,
+					"cond":  bson.M{"$eq": bson.A{"$$this.k", "$id"}},
+				}}}},
+				"in": bson.M{"$ifNull": bson.A{"$$m.v", 0}},
+			}},
+		}},
+		{"$addFields": bson.M{
+			"_score": bson.M{"$add": bson.A{
+				bson.M{"$multiply": bson.A{genreWeight, "$_genreScore"}},
+				bson.M{"$multiply": bson.A{coOccurrenceWeight, "$_coOccurrenceScore"}},
+			}},
+			"_lead": bson.M{"$eq": bson.A{"$id", seed.leadTrackID}},
+		}},
+		{"$sort": bson.D{
+			{Key: "_lead", Value: -1},
+			{Key: "_score", Value: -1},
+			{Key: "createdAt", Value: -1},
+			{Key: "id", Value: 1},
+		}},
+		{"$limit": limit},
+		{"$project": bson.M{"_id": 0, "id": 1}},
+	}
+
+	cur, err := s.tracksCol.Aggregate(ctx, pipeline)
+	if err != nil {
+		logger.Warn("failed to aggregate", slog.String("error", err.Error()))
+		return nil, errors.New("failed to pick tracks")
+	}
+	defer cur.Close(ctx)
+
+	var tracks []struct {
+		ID string `bson:"id"`
+	}
+	if err := cur.All(ctx, &tracks); err != nil {
+		return nil, errors.New("failed to decode tracks")
+	}
+
+	if len(tracks) == 0 {
+		return nil, ErrNoSimilarTracks
+	}
+
+	trackIDs := make([]string, len(tracks))
+	for i, t := range tracks {
+		trackIDs[i] = t.ID
+	}
+	return trackIDs, nil
+}
+
+// getCoOccurrence counts how often tracks appear together with seed tracks
+// in public playlists of other users, weights are normalized to [0, 1]
+func (s *PlaylistRecommendationsService) getCoOccurrence(
+	ctx context.Context, userID string, seedTrackIDs []string,
+) ([]weightedTrack, error) {
+	if len(seedTrackIDs) == 0 {
+		return []weightedTrack{}, nil
+	}
+
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"isPublic": true,
+			"userID":   bson.M{"$ne": userID},
+			"trackIDs": bson.M{"$in": seedTrackIDs},
+		}},
+		{"$unwind": "$trackIDs"},
+		{"$match": bson.M{"trackIDs": bson.M{"$nin": seedTrackIDs}}},
+		{"$group": bson.M{"_id": "$trackIDs", "count": bson.M{"$sum": 1}}},
+		{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+		{"$limit": coOccurrenceLimit},
+	}
+
+	cur, err := s.playlistsCol.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var counts []struct {
+		TrackID string  `bson:"_id"`
+		Count   float64 `bson:"count"`
+	}
+	if err := cur.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+
+	res := make([]weightedTrack, len(counts))
+	for i, c := range counts {
+		// sorted by count, the first one is max
+		res[i] = weightedTrack{TrackID: c.TrackID, Weight: c.Count / counts[0].Count}
+	}
+	return res, nil
+}
+
+// distinct returns distinct string values of field
+func (s *PlaylistRecommendationsService) distinct(
+	ctx context.Context, col *mongo.Collection, field string, filter bson.M,
+) ([]string, error) {
+	values := []string{}
+	err := col.Distinct(ctx, field, filter).Decode(&values)
+	return values, err
+}
+
+// findOne decodes document matching filter
+func findOne(ctx context.Context, col *mongo.Collection, filter bson.M, target any) error {
+	err := col.FindOne(ctx, filter).Decode(target)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return service.ErrNotFound
+		}
+		return errors.New("failed to find seed")
+	}
+	return nil
+}
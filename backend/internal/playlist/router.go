@@ -2,17 +2,43 @@ package playlist
 
 import (
 	"tracker-backend/internal/auth"
+	"tracker-backend/internal/auth/ownership"
+	playlistRecommendations "tracker-backend/internal/playlist/recommendations"
+	playlistTracks "tracker-backend/internal/playlist/tracks"
 
 	"github.com/go-chi/chi/v5"
 )
 
-func RegisterPlaylistRoutes(router chi.Router, service *PlaylistService, authMiddleware auth.MiddlewareFunc) {
+func RegisterPlaylistRoutes(
+	router chi.Router,
+	service *PlaylistService,
+	playlistTracksSvc *playlistTracks.PlaylistTracksService,
+	playlistRecommendationsSvc *playlistRecommendations.PlaylistRecommendationsService,
+	ownershipSvc *ownership.OwnershipService,
+	authMiddleware auth.MiddlewareFunc,
+) {
 	h := NewPlaylistHandler(service)
+	ht := playlistTracks.NewPlaylistTracksHandler(playlistTracksSvc)
+	hr := playlistRecommendations.NewPlaylistRecommendationsHandler(playlistRecommendationsSvc)
 	router.Route("/playlist", func(r chi.Router) {
 		r.Use(authMiddleware)
-		r.Get("/", h.MyPlaylists)       // get my playlists
-		r.Get("/{id}/tracks", h.Tracks) // get tracks in playlist
-		r.Put("/{id}/tracks/{trackID}", h.AddTrack)
-		r.Delete("/{id}/tracks/{trackID}", h.RemoveTrack)
+		r.Get("/", h.MyPlaylists)           // get my playlists
+		r.Post("/", h.Create)               // create playlist
+		r.Get("/{id}/tracks", ht.GetTracks) // get tracks in playlist
+		r.Get("/user/{userID}", h.UserPlaylists)
+		r.Post("/recommendations", hr.Generate)
+
+		// owner only
+		r.Group(func(r chi.Router) {
+			r.Use(ownershipSvc.Require(ownership.ResourcePlaylist, ownership.NoBypass))
+			r.Put("/{id}", h.Update)
+			r.Delete("/{id}", h.Delete)
+			r.Post("/{id}/recommendations/refresh", hr.Refresh)
+			r.Post("/{id}/tracks", h.InsertTrack)
+			r.Put("/{id}/tracks", h.ReorderTracks)
+			r.Put("/{id}/tracks/move", h.MoveTrack)
+			r.Put("/{id}/tracks/{trackID}", h.AddTrack)
+			r.Delete("/{id}/tracks/{trackID}", h.RemoveTrack)
+		})
 	})
 }
@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"slices"
 	"time"
+	"tracker-backend/internal/genre"
+	"tracker-backend/internal/pkg/pagination"
 	"tracker-backend/internal/pkg/service"
 	playlistType "tracker-backend/internal/playlist/type"
 
@@ -14,15 +17,32 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
+var (
+	ErrInvalidPosition = errors.New("position is out of range")
+	ErrNotPermutation  = errors.New("track ids must be a permutation of playlist tracks")
+	ErrTrackNotFound   = errors.New("track not found")
+	ErrTrackNotLinked  = errors.New("track is not in playlist")
+)
+
+// GenreModelBuilder adds genres of tracks added to playlists to user genre model
+type GenreModelBuilder interface {
+	AddTrack(ctx context.Context, userID, trackID string, weight float64) error
+}
+
 type PlaylistService struct {
-	Col *mongo.Collection
+	Col               *mongo.Collection
+	tracksCol         *mongo.Collection
+	genreModelBuilder GenreModelBuilder
 }
 
 func NewPlaylistService(
-	playlistCol *mongo.Collection,
+	playlistCol *mongo.Collection, tracksCol *mongo.Collection,
+	genreModelBuilder GenreModelBuilder,
 ) *PlaylistService {
 	return &PlaylistService{
-		Col: playlistCol,
+		Col:               playlistCol,
+		tracksCol:         tracksCol,
+		genreModelBuilder: genreModelBuilder,
 	}
 }
 
@@ -38,12 +58,20 @@ func (s *PlaylistService) Create(
 		UserID:    req.UserID,
 		IsDefault: req.IsDefault,
 		IsPublic:  req.IsPublic,
+		TrackIDs:  []string{},
+		Seed:      req.Seed,
 		UpdatedAt: time.Now(),
 	}
+	if req.TrackIDs != nil {
+		playlist.TrackIDs = req.TrackIDs
+	}
 
 	_, err := s.Col.InsertOne(ctx, playlist)
 
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, playlistType.ErrNameTaken
+		}
 		return nil, errors.New("failed to insert")
 	}
 
@@ -58,55 +86,135 @@ func (s *PlaylistService) Create(
 	return playlist, nil
 }
 
-func (s *PlaylistService) PushTrackLink(
-	ctx context.Context, playlistID string, trackID string,
+// Update updates name and visibility of playlist
+// playlist is loaded by ownership middleware
+func (s *PlaylistService) Update(
+	ctx context.Context, playlist *playlistType.Playlist, req *playlistType.UpdateRequest,
 ) (*playlistType.Playlist, error) {
 	// configure logger
-	logger := slog.With(slog.String("function", "playlist.PlaylistService.PushTrack"))
+	logger := slog.With(slog.String("function", "playlist.PlaylistService.Update"))
 
-	update := bson.M{
-		"$addToSet": bson.M{"trackIDs": trackID}, // Используем addToSet для избежания дубликатов
-		"$set":      bson.M{"updatedAt": time.Now()},
+	if playlist.IsDefault {
+		return nil, playlistType.ErrDefaultPlaylist
 	}
 
-	// find and update playlist
-	res := s.Col.FindOneAndUpdate(
-		ctx,
-		bson.M{"id": playlistID},
-		update,
+	updates := bson.M{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.IsPublic != nil {
+		updates["isPublic"] = *req.IsPublic
+	}
+	if len(updates) == 0 {
+		return nil, playlistType.ErrNothingToUpdate
+	}
+	updates["updatedAt"] = time.Now()
+
+	var updated playlistType.Playlist
+	err := s.Col.FindOneAndUpdate(ctx,
+		bson.M{"id": playlist.ID},
+		bson.M{"$set": updates},
 		options.FindOneAndUpdate().SetReturnDocument(options.After),
-	)
-	if res.Err() != nil {
-		logger.Warn("failed to update the playlist", slog.String("error", res.Err().Error()))
-		if errors.Is(res.Err(), mongo.ErrNoDocuments) {
+	).Decode(&updated)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, service.ErrNotFound
 		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, playlistType.ErrNameTaken
+		}
+		logger.Warn("failed to update playlist", slog.String("error", err.Error()))
 		return nil, errors.New("failed to update playlist")
 	}
 
-	// decode updated playlist
-	var updatedPlaylist playlistType.Playlist
-	err := res.Decode(&updatedPlaylist)
+	return &updated, nil
+}
+
+// Delete removes playlist, default playlist can't be deleted
+// playlist is loaded by ownership middleware
+func (s *PlaylistService) Delete(
+	ctx context.Context, playlist *playlistType.Playlist,
+) error {
+	// configure logger
+	logger := slog.With(slog.String("function", "playlist.PlaylistService.Delete"))
+
+	if playlist.IsDefault {
+		return playlistType.ErrDefaultPlaylist
+	}
+
+	res, err := s.Col.DeleteOne(ctx, bson.M{"id": playlist.ID})
 	if err != nil {
-		logger.Warn("failed to decode updated playlist", slog.String("error", err.Error()))
-		return nil, errors.New("failed to decode updated playlist")
+		logger.Warn("failed to delete playlist", slog.String("error", err.Error()))
+		return errors.New("failed to delete playlist")
+	}
+	if res.DeletedCount < 1 {
+		return service.ErrNotFound
 	}
 
-	// return updated playlist
-	return &updatedPlaylist, nil
+	logger.Info("playlist deleted",
+		slog.Group("info",
+			slog.String("userID", playlist.UserID),
+			slog.String("id", playlist.ID),
+		),
+	)
 
+	return nil
 }
 
-func (s *PlaylistService) RemoveTrackLink(
+// GetByUser returns playlists of user, default playlist goes first
+// and others are sorted by update time. onlyPublic hides private playlists
+func (s *PlaylistService) GetByUser(
+	ctx context.Context, userID string, onlyPublic bool, params pagination.Params,
+) ([]playlistType.Playlist, int64, error) {
+	filter := bson.M{"userID": userID}
+	if onlyPublic {
+		filter["isPublic"] = true
+	}
+
+	total, err := s.Col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, errors.New("failed to count playlists")
+	}
+
+	cur, err := s.Col.Find(ctx, filter,
+		options.Find().
+			SetSort(bson.D{
+				{Key: "isDefault", Value: -1},
+				{Key: "updatedAt", Value: -1},
+			}).
+			SetSkip(params.Skip()).
+			SetLimit(params.Limit),
+	)
+	if err != nil {
+		return nil, 0, errors.New("failed to find playlists")
+	}
+	defer cur.Close(ctx)
+
+	playlists := []playlistType.Playlist{}
+	if err := cur.All(ctx, &playlists); err != nil {
+		return nil, 0, errors.New("failed to decode cursor")
+	}
+
+	return playlists, total, nil
+}
+
+// PushTrackLink appends track link to playlist without version check,
+// it is kept for old clients, InsertTrack should be used instead.
+// Genres of added track are added to genre model of playlist owner
+func (s *PlaylistService) PushTrackLink(
 	ctx context.Context, playlistID string, trackID string,
 ) (*playlistType.Playlist, error) {
 	// configure logger
-	logger := slog.With(slog.String("function", "playlist.PlaylistService.PushTrack"))
+	logger := slog.With(slog.String("function", "playlist.PlaylistService.PushTrackLink"))
+
+	if err := s.checkTrackExists(ctx, trackID); err != nil {
+		return nil, err
+	}
 
-	// define updates
 	update := bson.M{
-		"$pull": bson.M{"trackIDs": trackID},
+		"$push": bson.M{"trackIDs": trackID},
 		"$set":  bson.M{"updatedAt": time.Now()},
+		"$inc":  bson.M{"version": 1},
 	}
 
 	// find and update playlist
@@ -132,87 +240,248 @@ func (s *PlaylistService) RemoveTrackLink(
 		return nil, errors.New("failed to decode updated playlist")
 	}
 
+	err = s.genreModelBuilder.AddTrack(ctx, updatedPlaylist.UserID, trackID, genre.WeightPlaylist)
+	if err != nil {
+		logger.Warn("failed to update genre model", slog.String("error", err.Error()))
+	}
+
 	// return updated playlist
 	return &updatedPlaylist, nil
 }
 
-// TODO: remove invalid links
-/*
-func (s *PlaylistService) RemoveInvalidLinks(ctx context.Context) error {
-	// 1. Получаем все существующие ID треков
-	trackCol := s.Col.Database().Collection("tracks")
-	existingTrackIDs, err := getExistingTrackIDs(ctx, trackCol)
+// RemoveTrackLink removes the first link of track from playlist
+// playlist is loaded by ownership middleware, version must match
+func (s *PlaylistService) RemoveTrackLink(
+	ctx context.Context, playlist *playlistType.Playlist, trackID string, version int64,
+) (*playlistType.Playlist, error) {
+	if playlist.Version != version {
+		return nil, playlistType.ErrVersionConflict
+	}
+
+	i := slices.Index(playlist.TrackIDs, trackID)
+	if i < 0 {
+		return nil, ErrTrackNotLinked
+	}
+	trackIDs := slices.Delete(slices.Clone(playlist.TrackIDs), i, i+1)
+
+	return s.SetTrackIDs(ctx, playlist.ID, playlist.Version, trackIDs)
+}
+
+// InsertTrack inserts track link at position
+// playlist version must match request version.
+// Genres of added track are added to genre model of playlist owner
+func (s *PlaylistService) InsertTrack(
+	ctx context.Context, playlistID string, req *playlistType.InsertTrackRequest,
+) (*playlistType.Playlist, error) {
+	// configure logger
+	logger := slog.With(slog.String("function", "playlist.PlaylistService.InsertTrack"))
+
+	if err := s.checkTrackExists(ctx, req.TrackID); err != nil {
+		return nil, err
+	}
+
+	push := bson.M{"$each": bson.A{req.TrackID}}
+	if req.Position != nil {
+		push["$position"] = *req.Position
+	}
+
+	updated, err := s.updateVersioned(ctx, playlistID, *req.Version, bson.M{
+		"$push": bson.M{"trackIDs": push},
+		"$set":  bson.M{"updatedAt": time.Now()},
+		"$inc":  bson.M{"version": 1},
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// 2. Находим плейлисты с несуществующими треками
-	pipeline := []bson.M{
-		{
-			"$project": bson.M{
-				"trackIDs": 1,
-				"invalidTracks": bson.M{
-					"$setDifference": bson.A{
-						"$trackIDs",
-						existingTrackIDs,
-					},
-				},
-			},
-		},
-		{
-			"$match": bson.M{
-				"invalidTracks": bson.M{"$ne": bson.A{}},
-			},
-		},
+	err = s.genreModelBuilder.AddTrack(ctx, updated.UserID, req.TrackID, genre.WeightPlaylist)
+	if err != nil {
+		logger.Warn("failed to update genre model", slog.String("error", err.Error()))
+	}
+
+	return updated, nil
+}
+
+// MoveTrack moves track link from one index to another
+// playlist is loaded by ownership middleware
+func (s *PlaylistService) MoveTrack(
+	ctx context.Context, playlist *playlistType.Playlist, req *playlistType.MoveTrackRequest,
+) (*playlistType.Playlist, error) {
+	if playlist.Version != *req.Version {
+		return nil, playlistType.ErrVersionConflict
+	}
+
+	from, to := *req.From, *req.To
+	if from >= len(playlist.TrackIDs) || to >= len(playlist.TrackIDs) {
+		return nil, ErrInvalidPosition
+	}
+
+	// remove track and insert it to new position
+	trackIDs := slices.Clone(playlist.TrackIDs)
+	trackID := trackIDs[from]
+	trackIDs = slices.Delete(trackIDs, from, from+1)
+	trackIDs = slices.Insert(trackIDs, to, trackID)
+
+	return s.SetTrackIDs(ctx, playlist.ID, playlist.Version, trackIDs)
+}
+
+// ReorderTracks replaces order of track links
+// playlist is loaded by ownership middleware
+func (s *PlaylistService) ReorderTracks(
+	ctx context.Context, playlist *playlistType.Playlist, req *playlistType.ReorderTracksRequest,
+) (*playlistType.Playlist, error) {
+	if playlist.Version != *req.Version {
+		return nil, playlistType.ErrVersionConflict
 	}
 
-	cursor, err := s.Col.Aggregate(ctx, pipeline)
+	// new order must contain the same links
+	current := slices.Clone(playlist.TrackIDs)
+	requested := slices.Clone(req.TrackIDs)
+	slices.Sort(current)
+	slices.Sort(requested)
+	if !slices.Equal(current, requested) {
+		return nil, ErrNotPermutation
+	}
+
+	return s.SetTrackIDs(ctx, playlist.ID, playlist.Version, req.TrackIDs)
+}
+
+// SetTrackIDs replaces track links of playlist with expected version
+func (s *PlaylistService) SetTrackIDs(
+	ctx context.Context, playlistID string, version int64, trackIDs []string,
+) (*playlistType.Playlist, error) {
+	return s.updateVersioned(ctx, playlistID, version, bson.M{
+		"$set": bson.M{
+			"trackIDs":  trackIDs,
+			"updatedAt": time.Now(),
+		},
+		"$inc": bson.M{"version": 1},
+	})
+}
+
+// checkTrackExists returns ErrTrackNotFound if track doesn't exist,
+// so links to unknown tracks aren't stored
+func (s *PlaylistService) checkTrackExists(ctx context.Context, trackID string) error {
+	// configure logger
+	logger := slog.With(slog.String("function", "playlist.PlaylistService.checkTrackExists"))
+
+	count, err := s.tracksCol.CountDocuments(ctx, bson.M{"id": trackID})
 	if err != nil {
-		return err
+		logger.Warn("failed to check track existence", slog.String("error", err.Error()))
+		return errors.New("failed to check track existence")
 	}
-	defer cursor.Close(ctx)
+	if count < 1 {
+		return ErrTrackNotFound
+	}
+	return nil
+}
 
-	// 3. Обновляем плейлисты, удаляя невалидные ссылки
-	for cursor.Next(ctx) {
-		var result struct {
-			ID           string   `bson:"_id"`
-			InvalidTracks []string `bson:"invalidTracks"`
-		}
-		if err := cursor.Decode(&result); err != nil {
-			continue
-		}
+// updateVersioned updates playlist only if its version matches expected one
+func (s *PlaylistService) updateVersioned(
+	ctx context.Context, playlistID string, version int64, update bson.M,
+) (*playlistType.Playlist, error) {
+	// configure logger
+	logger := slog.With(slog.String("function", "playlist.PlaylistService.updateVersioned"))
 
-		_, err := s.Col.UpdateByID(
-			ctx,
-			result.ID,
-			bson.M{"$pull": bson.M{"trackIDs": bson.M{"$in": result.InvalidTracks}}},
-		)
+	// playlists created before versioning have no version field
+	filter := bson.M{"id": playlistID, "version": version}
+	if version == 0 {
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	var updated playlistType.Playlist
+	err := s.Col.FindOneAndUpdate(ctx,
+		filter,
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			logger.Warn("failed to update the playlist", slog.String("error", err.Error()))
+			return nil, errors.New("failed to update playlist")
+		}
+		// check playlist existence
+		count, err := s.Col.CountDocuments(ctx, bson.M{"id": playlistID})
 		if err != nil {
-			// Логируем ошибку, но продолжаем обработку
-			continue
+			return nil, errors.New("failed to check playlist existence")
 		}
+		if count < 1 {
+			return nil, service.ErrNotFound
+		}
+		return nil, playlistType.ErrVersionConflict
 	}
 
-	return nil
+	return &updated, nil
 }
 
-func getExistingTrackIDs(ctx context.Context, col *mongo.Collection) (bson.A, error) {
-	cursor, err := col.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"_id": 1}))
+// RemoveInvalidLinks pulls ids of deleted tracks from all playlists,
+// returns number of updated playlists
+func (s *PlaylistService) RemoveInvalidLinks(ctx context.Context) (int64, error) {
+	// configure logger
+	logger := slog.With(slog.String("function", "playlist.PlaylistService.RemoveInvalidLinks"))
+
+	// find playlists with links to missing tracks
+	pipeline := []bson.M{
+		{"$match": bson.M{"trackIDs.0": bson.M{"$exists": true}}},
+		{"$lookup": bson.M{
+			"from":         "tracks",
+			"localField":   "trackIDs",
+			"foreignField": "id",
+			"pipeline":     bson.A{bson.M{"$project": bson.M{"_id": 0, "id": 1}}},
+			"as":           "tracks",
+		}},
+		{"$project": bson.M{
+			"_id": 0,
+			"id":  1,
+			"invalidTrackIDs": bson.M{"$setDifference": bson.A{
+				"$trackIDs", "$tracks.id",
+			}},
+		}},
+		{"$match": bson.M{"invalidTrackIDs.0": bson.M{"$exists": true}}},
+	}
+
+	cur, err := s.Col.Aggregate(ctx, pipeline)
 	if err != nil {
-		return nil, err
+		logger.Warn("failed to aggregate", slog.String("error", err.Error()))
+		return 0, errors.New("failed to find invalid links")
 	}
-	defer cursor.Close(ctx)
+	defer cur.Close(ctx)
 
-	var ids bson.A
-	for cursor.Next(ctx) {
-		var doc struct {
-			ID string `bson:"id"`
+	var updated int64
+	for cur.Next(ctx) {
+		var res struct {
+			ID              string   `bson:"id"`
+			InvalidTrackIDs []string `bson:"invalidTrackIDs"`
 		}
-		if err := cursor.Decode(&doc); err != nil {
-			continue
+		if err := cur.Decode(&res); err != nil {
+			return updated, errors.New("failed to decode playlist")
 		}
-		ids = append(ids, doc.ID)
+
+		_, err := s.Col.UpdateOne(ctx,
+			bson.M{"id": res.ID},
+			bson.M{
+				"$pull": bson.M{"trackIDs": bson.M{"$in": res.InvalidTrackIDs}},
+				"$set":  bson.M{"updatedAt": time.Now()},
+				"$inc":  bson.M{"version": 1},
+			},
+		)
+		if err != nil {
+			logger.Warn("failed to update playlist",
+				slog.String("id", res.ID),
+				slog.String("error", err.Error()),
+			)
+			return updated, errors.New("failed to remove invalid links")
+		}
+		updated++
 	}
-	return ids, nil
+	if err := cur.Err(); err != nil {
+		return updated, errors.New("failed to find invalid links")
+	}
+
+	if updated > 0 {
+		logger.Info("invalid links removed", slog.Int64("playlists", updated))
+	}
+
+	return updated, nil
 }
-*/
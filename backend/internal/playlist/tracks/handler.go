@@ -1 +1,64 @@
 package playlistTracks
+
+import (
+	"errors"
+	"net/http"
+	"tracker-backend/internal/auth"
+	"tracker-backend/internal/pkg/response"
+	"tracker-backend/internal/pkg/service"
+	playlistType "tracker-backend/internal/playlist/type"
+	"tracker-backend/internal/track"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+type PlaylistTracksHandler struct {
+	Service *PlaylistTracksService
+}
+
+func NewPlaylistTracksHandler(s *PlaylistTracksService) *PlaylistTracksHandler {
+	return &PlaylistTracksHandler{
+		Service: s,
+	}
+}
+
+// GET /playlist/{id}/tracks
+func (h *PlaylistTracksHandler) GetTracks(w http.ResponseWriter, r *http.Request) {
+	// get context keys
+	ctx := r.Context()
+	userID := ctx.Value(auth.UserIDKey).(string)
+	userRole, _ := ctx.Value(auth.UserRoleKey).(int)
+	playlistID := chi.URLParam(r, "id")
+
+	// execute service function
+	tracks, missingIDs, hiddenIDs, err := h.Service.GetTracks(ctx, playlistID, userID, userRole)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("playlist not found"))
+		case errors.Is(err, service.ErrAccessDenied):
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, response.Error(err.Error()))
+		default:
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(err.Error()))
+		}
+		return
+	}
+
+	// execution result to response
+	res := playlistType.PlaylistTracksResponse{
+		PlaylistID:      playlistID,
+		Tracks:          make([]track.TrackResponse, len(tracks)),
+		MissingTrackIDs: missingIDs,
+		HiddenTrackIDs:  hiddenIDs,
+	}
+	for i, t := range tracks {
+		res.Tracks[i] = t.ToResponse()
+	}
+
+	// send response
+	render.JSON(w, r, res)
+}
@@ -1 +1,133 @@
 package playlistTracks
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	albumType "tracker-backend/internal/album/type"
+	artistType "tracker-backend/internal/artist/type"
+	"tracker-backend/internal/auth"
+	"tracker-backend/internal/pkg/service"
+	playlistType "tracker-backend/internal/playlist/type"
+	"tracker-backend/internal/track"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type PlaylistTracksService struct {
+	playlistsCol *mongo.Collection
+	artistsCol   *mongo.Collection
+}
+
+func NewPlaylistTracksService(playlistsCol, artistsCol *mongo.Collection) *PlaylistTracksService {
+	return &PlaylistTracksService{
+		playlistsCol: playlistsCol,
+		artistsCol:   artistsCol,
+	}
+}
+
+// playlistWithTracks is a playlist with tracks resolved in order of track ids
+// deleted tracks are resolved to nil
+type playlistWithTracks struct {
+	playlistType.Playlist `bson:",inline"`
+	Tracks                []*track.Track `bson:"tracks"`
+	// albums of tracks visible to user, not set for moderators
+	VisibleAlbums []albumType.Album `bson:"_visibleAlbums"`
+}
+
+// GetTracks returns playlist tracks in playlist order,
+// ids of tracks which don't exist anymore and ids of tracks
+// of albums hidden from user.
+// Private playlist is available to its owner only
+func (s *PlaylistTracksService) GetTracks(
+	ctx context.Context, playlistID, userID string, userRole int,
+) ([]track.Track, []string, []string, error) {
+	// configure logger
+	logger := slog.With(slog.String("function", "playlistTracks.PlaylistTracksService.GetTracks"))
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"id": playlistID}},
+		{"$lookup": bson.M{
+			"from":         "tracks",
+			"localField":   "trackIDs",
+			"foreignField": "id",
+			"as":           "_tracks",
+		}},
+		// restore playlist order, lookup result is unordered
+		{"$addFields": bson.M{
+			"tracks": bson.M{"$map": bson.M{
+				"input": bson.M{"$ifNull": bson.A{"$trackIDs", bson.A{}}},
+				"as":    "trackID",
+				"in": bson.M{"$first": bson.M{"$filter": bson.M{
+					"input": "$_tracks",
+					"cond":  bson.M{"$eq": bson.A{"$$this.id", "$$trackID"}},
+				}}},
+			}},
+		}},
+	}
+
+	// moderators can see tracks of any album
+	restricted := userRole < auth.RoleModerator
+	if restricted {
+		ownArtistIDs, err := artistType.OwnArtistIDs(ctx, s.artistsCol, userID)
+		if err != nil {
+			logger.Warn("failed to get own artists", slog.String("error", err.Error()))
+			return nil, nil, nil, errors.New("failed to get playlist tracks")
+		}
+		pipeline = append(pipeline, bson.M{"$lookup": bson.M{
+			"from":         "albums",
+			"localField":   "_tracks.albumID",
+			"foreignField": "id",
+			"pipeline": bson.A{
+				bson.M{"$match": albumType.VisibilityFilter("", ownArtistIDs)},
+				bson.M{"$project": bson.M{"_id": 0, "id": 1}},
+			},
+			"as": "_visibleAlbums",
+		}})
+	}
+	pipeline = append(pipeline, bson.M{"$project": bson.M{"_tracks": 0}})
+
+	cur, err := s.playlistsCol.Aggregate(ctx, pipeline)
+	if err != nil {
+		logger.Warn("failed to aggregate", slog.String("error", err.Error()))
+		return nil, nil, nil, errors.New("failed to get playlist tracks")
+	}
+	defer cur.Close(ctx)
+
+	if !cur.Next(ctx) {
+		return nil, nil, nil, service.ErrNotFound
+	}
+	var playlist playlistWithTracks
+	if err := cur.Decode(&playlist); err != nil {
+		logger.Warn("failed to decode playlist", slog.String("error", err.Error()))
+		return nil, nil, nil, errors.New("failed to decode playlist")
+	}
+
+	// check access
+	if !playlist.IsPublic && playlist.UserID != userID {
+		return nil, nil, nil, service.ErrAccessDenied
+	}
+
+	visibleAlbums := make(map[string]bool, len(playlist.VisibleAlbums))
+	for _, a := range playlist.VisibleAlbums {
+		visibleAlbums[a.ID] = true
+	}
+
+	// split found, dangling and hidden tracks
+	tracks := make([]track.Track, 0, len(playlist.Tracks))
+	missingIDs := []string{}
+	hiddenIDs := []string{}
+	for i, t := range playlist.Tracks {
+		switch {
+		case t == nil:
+			missingIDs = append(missingIDs, playlist.TrackIDs[i])
+		case restricted && !visibleAlbums[t.AlbumID]:
+			hiddenIDs = append(hiddenIDs, t.ID)
+		default:
+			tracks = append(tracks, *t)
+		}
+	}
+
+	return tracks, missingIDs, hiddenIDs, nil
+}
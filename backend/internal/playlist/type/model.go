@@ -1,8 +1,125 @@
 package playlistType
 
+import (
+	"errors"
+	"tracker-backend/internal/track"
+)
+
+var (
+	ErrVersionConflict = errors.New("playlist was modified by another request")
+	ErrNameTaken       = errors.New("playlist with this name already exists")
+	ErrDefaultPlaylist = errors.New("default playlist can't be changed or deleted")
+	ErrNothingToUpdate = errors.New("nothing to update")
+)
+
+// PlaylistCreateRequest creates playlist,
+// user and default flag are set by server
 type PlaylistCreateRequest struct {
 	Name      string `json:"name" validate:"required,min=3,max=255"`
-	UserID    string `json:"userID" validate:"required,uuid4"`
-	IsPublic  bool   `json:"isPublic" validate:"required"`
-	IsDefault bool   `json:"isDefault" validate:"required"`
+	UserID    string `json:"-"`
+	IsPublic  bool   `json:"isPublic"`
+	IsDefault bool   `json:"-"`
+	// initial tracks and seed of generated playlist
+	TrackIDs []string `json:"-"`
+	Seed     *Seed    `json:"-"`
+}
+
+// UpdateRequest updates playlist metadata
+// nil fields are not updated
+type UpdateRequest struct {
+	Name     *string `json:"name" validate:"omitempty,min=3,max=255"`
+	IsPublic *bool   `json:"isPublic"`
+}
+
+// GenerateRequest creates playlist of tracks similar to seed
+type GenerateRequest struct {
+	SeedType string `json:"seedType" validate:"required,oneof=track album artist genre"`
+	Seed     string `json:"seed" validate:"required,max=128"` // id or genre name
+	Name     string `json:"name" validate:"omitempty,min=3,max=255"`
+	Limit    int    `json:"limit" validate:"omitempty,min=1,max=100"`
+	IsPublic bool   `json:"isPublic"`
+}
+
+type PlaylistResponse struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	UserID    string        `json:"userID"`
+	IsDefault bool          `json:"isDefault"`
+	IsPublic  bool          `json:"isPublic"`
+	TrackIDs  []string      `json:"trackIDs"`
+	Version   int64         `json:"version"`
+	Generated bool          `json:"generated"`
+	Seed      *SeedResponse `json:"seed,omitempty"`
+	UpdatedAt string        `json:"updatedAt"`
+}
+
+type PlaylistListResponse struct {
+	Items []PlaylistResponse `json:"items"`
+	Total int64              `json:"total"`
+	Page  int64              `json:"page"`
+	Limit int64              `json:"limit"`
+}
+
+type SeedResponse struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	Limit int    `json:"limit"`
+}
+
+// InsertTrackRequest inserts track at position
+// track is appended to the end if position is omitted
+type InsertTrackRequest struct {
+	TrackID  string `json:"trackID" validate:"required,uuid4"`
+	Position *int   `json:"position" validate:"omitempty,min=0"`
+	Version  *int64 `json:"version" validate:"required,min=0"`
+}
+
+// MoveTrackRequest moves track from one index to another
+type MoveTrackRequest struct {
+	From    *int   `json:"from" validate:"required,min=0"`
+	To      *int   `json:"to" validate:"required,min=0"`
+	Version *int64 `json:"version" validate:"required,min=0"`
+}
+
+// ReorderTracksRequest replaces tracks order,
+// track ids must be a permutation of playlist tracks
+type ReorderTracksRequest struct {
+	TrackIDs []string `json:"trackIDs" validate:"required,dive,uuid4"`
+	Version  *int64   `json:"version" validate:"required,min=0"`
+}
+
+type PlaylistTracksResponse struct {
+	PlaylistID string                `json:"playlistID"`
+	Tracks     []track.TrackResponse `json:"tracks"`
+	// ids of deleted tracks which are still linked to playlist
+	MissingTrackIDs []string `json:"missingTrackIDs"`
+	// ids of tracks of albums which are hidden or on moderation
+	HiddenTrackIDs []string `json:"hiddenTrackIDs"`
+}
+
+func (p *Playlist) ToResponse() PlaylistResponse {
+	trackIDs := p.TrackIDs
+	if trackIDs == nil {
+		trackIDs = []string{}
+	}
+	var seed *SeedResponse
+	if p.Seed != nil {
+		seed = &SeedResponse{
+			Type:  p.Seed.Type,
+			Value: p.Seed.Value,
+			Limit: p.Seed.Limit,
+		}
+	}
+	return PlaylistResponse{
+		ID:        p.ID,
+		Name:      p.Name,
+		UserID:    p.UserID,
+		IsDefault: p.IsDefault,
+		IsPublic:  p.IsPublic,
+		TrackIDs:  trackIDs,
+		Version:   p.Version,
+		Generated: p.Seed != nil,
+		Seed:      seed,
+		UpdatedAt: p.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
 }
@@ -16,9 +16,26 @@ type Playlist struct {
 	IsDefault bool      `bson:"isDefault"`
 	IsPublic  bool      `bson:"isPublic"`
 	TrackIDs  []string  `bson:"trackIDs"`
+	Version   int64     `bson:"version"`        // incremented on every tracks change
+	Seed      *Seed     `bson:"seed,omitempty"` // set for generated playlists
 	UpdatedAt time.Time `bson:"updatedAt"`
 }
 
+// Seed is a source of generated playlist,
+// playlist with seed can be refreshed
+type Seed struct {
+	Type  string `bson:"type"`
+	Value string `bson:"value"` // id of track, album, artist or genre name
+	Limit int    `bson:"limit"`
+}
+
+const (
+	SeedTrack  = "track"
+	SeedAlbum  = "album"
+	SeedArtist = "artist"
+	SeedGenre  = "genre"
+)
+
 func EnsureIndexes(ctx context.Context, col *mongo.Collection) error {
 	// unique index userID and name
 	nameUserIndex := mongo.IndexModel{
@@ -35,6 +52,16 @@ func EnsureIndexes(ctx context.Context, col *mongo.Collection) error {
 		Options: options.Index().SetUnique(true).SetName("id_unique"),
 	}
 
-	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{nameUserIndex, idIndex})
+	// index for user playlists sorted by default flag and update time
+	userIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "userID", Value: 1},
+			{Key: "isDefault", Value: -1},
+			{Key: "updatedAt", Value: -1},
+		},
+		Options: options.Index().SetName("userID_isDefault_updatedAt"),
+	}
+
+	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{nameUserIndex, idIndex, userIndex})
 	return err
 }
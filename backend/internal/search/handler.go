@@ -0,0 +1,62 @@
+package search
+
+import (
+	"net/http"
+	"strings"
+	"tracker-backend/internal/auth"
+	"tracker-backend/internal/pkg/pagination"
+	"tracker-backend/internal/pkg/response"
+	searchType "tracker-backend/internal/search/type"
+
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type SearchHandler struct {
+	Service   *SearchService
+	Validator *validator.Validate
+}
+
+func NewSearchHandler(s *SearchService) *SearchHandler {
+	v := validator.New()
+	return &SearchHandler{
+		Service:   s,
+		Validator: v,
+	}
+}
+
+// GET /resource/search?query=&type=tracks,albums,artists&page=&limit=
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	// get context keys (user may be anonymous)
+	ctx := r.Context()
+	userID, _ := ctx.Value(auth.UserIDKey).(string)
+	userRole, _ := ctx.Value(auth.UserRoleKey).(int)
+
+	// create request from query params
+	req := &searchType.SearchRequest{
+		Query:  strings.TrimSpace(r.URL.Query().Get("query")),
+		Groups: searchType.AllGroups,
+		Params: pagination.FromRequest(r),
+	}
+	if groups := r.URL.Query().Get("type"); groups != "" {
+		req.Groups = strings.Split(groups, ",")
+	}
+
+	// validate request
+	if err := h.Validator.Struct(req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.ValidationErrorsResp(err.(validator.ValidationErrors)))
+		return
+	}
+
+	// execute service function
+	res, err := h.Service.Search(ctx, req, userID, userRole)
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
+
+	// send response
+	render.JSON(w, r, res)
+}
@@ -0,0 +1,20 @@
+package search
+
+import (
+	"tracker-backend/internal/auth"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func RegisterSearchRoutes(
+	router chi.Router,
+	service *SearchService,
+	optionalAuthMiddleware auth.MiddlewareFunc,
+) {
+	h := NewSearchHandler(service)
+
+	router.Route("/resource", func(r chi.Router) {
+		r.Use(optionalAuthMiddleware)
+		r.Get("/search", h.Search)
+	})
+}
@@ -0,0 +1,249 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	albumType "tracker-backend/internal/album/type"
+	artistType "tracker-backend/internal/artist/type"
+	"tracker-backend/internal/auth"
+	searchType "tracker-backend/internal/search/type"
+	"tracker-backend/internal/track"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type SearchService struct {
+	tracksCol  *mongo.Collection
+	albumsCol  *mongo.Collection
+	artistsCol *mongo.Collection
+}
+
+func NewSearchService(
+	tracksCol, albumsCol, artistsCol *mongo.Collection,
+) *SearchService {
+	return &SearchService{
+		tracksCol:  tracksCol,
+		albumsCol:  albumsCol,
+		artistsCol: artistsCol,
+	}
+}
+
+// visibilityScope describes which albums user is able to find
+type visibilityScope struct {
+	restricted   bool
+	ownArtistIDs []string
+}
+
+// facetResult is a result of paginated aggregation
+type facetResult[T any] struct {
+	Items []T `bson:"items"`
+	Total []struct {
+		Count int64 `bson:"count"`
+	} `bson:"total"`
+}
+
+func (f *facetResult[T]) total() int64 {
+	if len(f.Total) == 0 {
+		return 0
+	}
+	return f.Total[0].Count
+}
+
+// Search finds tracks, albums and artists by text query
+// results in each group are sorted by relevance
+func (s *SearchService) Search(
+	ctx context.Context, req *searchType.SearchRequest, userID string, userRole int,
+) (*searchType.SearchResponse, error) {
+	// configure logger
+	logger := slog.With(slog.String("function", "search.SearchService.Search"))
+
+	res := &searchType.SearchResponse{
+		Query: req.Query,
+		Page:  req.Page,
+		Limit: req.Limit,
+	}
+
+	// moderators can find any album and track
+	visibility := &visibilityScope{}
+	if userRole < auth.RoleModerator {
+		ownArtistIDs, err := artistType.OwnArtistIDs(ctx, s.artistsCol, userID)
+		if err != nil {
+			logger.Warn("failed to get own artists", slog.String("error", err.Error()))
+			return nil, errors.New("failed to search")
+		}
+		visibility.restricted = true
+		visibility.ownArtistIDs = ownArtistIDs
+	}
+
+	if slices.Contains(req.Groups, searchType.GroupTracks) {
+		tracks, err := s.searchTracks(ctx, req, visibility)
+		if err != nil {
+			logger.Warn("failed to search tracks", slog.String("error", err.Error()))
+			return nil, errors.New("failed to search tracks")
+		}
+		res.Tracks = tracks
+	}
+
+	if slices.Contains(req.Groups, searchType.GroupAlbums) {
+		albums, err := s.searchAlbums(ctx, req, visibility)
+		if err != nil {
+			logger.Warn("failed to search albums", slog.String("error", err.Error()))
+			return nil, errors.New("failed to search albums")
+		}
+		res.Albums = albums
+	}
+
+	if slices.Contains(req.Groups, searchType.GroupArtists) {
+		artists, err := s.searchArtists(ctx, req)
+		if err != nil {
+			logger.Warn("failed to search artists", slog.String("error", err.Error()))
+			return nil, errors.New("failed to search artists")
+		}
+		res.Artists = artists
+	}
+
+	return res, nil
+}
+
+func (s *SearchService) searchTracks(
+	ctx context.Context, req *searchType.SearchRequest, visibility *visibilityScope,
+) (*searchType.TracksGroup, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"$text": bson.M{"$search": req.Query}}},
+		{"$addFields": bson.M{"score": bson.M{"$meta": "textScore"}}},
+	}
+
+	// track is visible only if its album is visible
+	if visibility.restricted {
+		pipeline = append(pipeline,
+			bson.M{
+				"$lookup": bson.M{
+					"from":         "albums",
+					"localField":   "album",
+					"foreignField": "id",
+					"as":           "albumDoc",
+				},
+			},
+			bson.M{"$unwind": "$albumDoc"},
+			bson.M{"$match": albumType.VisibilityFilter("albumDoc.", visibility.ownArtistIDs)},
+			bson.M{"$project": bson.M{"albumDoc": 0}},
+		)
+	}
+	pipeline = append(pipeline, sortAndPaginate(req, "title")...)
+
+	var result facetResult[struct {
+		track.Track `bson:",inline"`
+		Score       float64 `bson:"score"`
+	}]
+	if err := aggregateOne(ctx, s.tracksCol, pipeline, &result); err != nil {
+		return nil, err
+	}
+
+	group := &searchType.TracksGroup{
+		Items: make([]searchType.TrackHit, len(result.Items)),
+		Total: result.total(),
+	}
+	for i, t := range result.Items {
+		group.Items[i] = searchType.TrackHit{TrackResponse: t.ToResponse(), Score: t.Score}
+	}
+	return group, nil
+}
+
+func (s *SearchService) searchAlbums(
+	ctx context.Context, req *searchType.SearchRequest, visibility *visibilityScope,
+) (*searchType.AlbumsGroup, error) {
+	match := bson.M{"$text": bson.M{"$search": req.Query}}
+	if visibility.restricted {
+		for key, value := range albumType.VisibilityFilter("", visibility.ownArtistIDs) {
+			match[key] = value
+		}
+	}
+
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$addFields": bson.M{"score": bson.M{"$meta": "textScore"}}},
+	}
+	pipeline = append(pipeline, sortAndPaginate(req, "title")...)
+
+	var result facetResult[struct {
+		albumType.Album `bson:",inline"`
+		Score           float64 `bson:"score"`
+	}]
+	if err := aggregateOne(ctx, s.albumsCol, pipeline, &result); err != nil {
+		return nil, err
+	}
+
+	group := &searchType.AlbumsGroup{
+		Items: make([]searchType.AlbumHit, len(result.Items)),
+		Total: result.total(),
+	}
+	for i, a := range result.Items {
+		group.Items[i] = searchType.AlbumHit{AlbumResponse: a.ToResponse(), Score: a.Score}
+	}
+	return group, nil
+}
+
+func (s *SearchService) searchArtists(
+	ctx context.Context, req *searchType.SearchRequest,
+) (*searchType.ArtistsGroup, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"$text": bson.M{"$search": req.Query}}},
+		{"$addFields": bson.M{"score": bson.M{"$meta": "textScore"}}},
+	}
+	pipeline = append(pipeline, sortAndPaginate(req, "name")...)
+
+	var result facetResult[struct {
+		artistType.Artist `bson:",inline"`
+		Score             float64 `bson:"score"`
+	}]
+	if err := aggregateOne(ctx, s.artistsCol, pipeline, &result); err != nil {
+		return nil, err
+	}
+
+	group := &searchType.ArtistsGroup{
+		Items: make([]searchType.ArtistHit, len(result.Items)),
+		Total: result.total(),
+	}
+	for i, a := range result.Items {
+		group.Items[i] = searchType.ArtistHit{Artist: a.Artist, Score: a.Score}
+	}
+	return group, nil
+}
+
+// sortAndPaginate sorts documents by text score
+// and splits result to requested page and total count
+func sortAndPaginate(req *searchType.SearchRequest, tieBreaker string) []bson.M {
+	return []bson.M{
+		{"$sort": bson.D{{Key: "score", Value: -1}, {Key: tieBreaker, Value: 1}}},
+		{
+			"$facet": bson.M{
+				"items": bson.A{
+					bson.M{"$skip": req.Skip()},
+					bson.M{"$limit": req.Limit},
+				},
+				"total": bson.A{
+					bson.M{"$count": "count"},
+				},
+			},
+		},
+	}
+}
+
+// aggregateOne decodes the first document of aggregation result
+func aggregateOne(
+	ctx context.Context, col *mongo.Collection, pipeline []bson.M, target any,
+) error {
+	cur, err := col.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	if cur.Next(ctx) {
+		return cur.Decode(target)
+	}
+	return cur.Err()
+}
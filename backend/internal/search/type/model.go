@@ -0,0 +1,61 @@
+package searchType
+
+import (
+	albumType "tracker-backend/internal/album/type"
+	artistType "tracker-backend/internal/artist/type"
+	"tracker-backend/internal/pkg/pagination"
+	"tracker-backend/internal/track"
+)
+
+const (
+	GroupTracks  = "tracks"
+	GroupAlbums  = "albums"
+	GroupArtists = "artists"
+)
+
+var AllGroups = []string{GroupTracks, GroupAlbums, GroupArtists}
+
+type SearchRequest struct {
+	Query  string   `validate:"required,min=2,max=128"`
+	Groups []string `validate:"required,dive,oneof=tracks albums artists"`
+	pagination.Params
+}
+
+type TrackHit struct {
+	track.TrackResponse
+	Score float64 `json:"score"`
+}
+
+type AlbumHit struct {
+	albumType.AlbumResponse
+	Score float64 `json:"score"`
+}
+
+type ArtistHit struct {
+	artistType.Artist
+	Score float64 `json:"score"`
+}
+
+type TracksGroup struct {
+	Items []TrackHit `json:"items"`
+	Total int64      `json:"total"`
+}
+
+type AlbumsGroup struct {
+	Items []AlbumHit `json:"items"`
+	Total int64      `json:"total"`
+}
+
+type ArtistsGroup struct {
+	Items []ArtistHit `json:"items"`
+	Total int64       `json:"total"`
+}
+
+type SearchResponse struct {
+	Query   string        `json:"query"`
+	Page    int64         `json:"page"`
+	Limit   int64         `json:"limit"`
+	Tracks  *TracksGroup  `json:"tracks,omitempty"`
+	Albums  *AlbumsGroup  `json:"albums,omitempty"`
+	Artists *ArtistsGroup `json:"artists,omitempty"`
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+	"tracker-backend/internal/auth"
+	"tracker-backend/internal/pkg/clientip"
+	"tracker-backend/internal/pkg/response"
+	"tracker-backend/internal/pkg/storage"
+
+	"github.com/go-chi/render"
+)
+
+const rateLimitKeyPrefix = "ratelimit:"
+
+type RateLimiter interface {
+	TakeToken(ctx context.Context, key string, limit int64, window time.Duration) (storage.RateLimitResult, error)
+}
+
+// RateLimitKeyFunc returns identity of request client the limit is applied to
+type RateLimitKeyFunc func(r *http.Request) string
+
+// KeyByIP limits requests by client ip
+func KeyByIP(r *http.Request) string {
+	return "ip:" + clientip.FromRequest(r)
+}
+
+// KeyByUser limits requests by authorized user,
+// anonymous requests are limited by ip
+func KeyByUser(r *http.Request) string {
+	if userID, ok := r.Context().Value(auth.UserIDKey).(string); ok {
+		return "user:" + userID
+	}
+	return KeyByIP(r)
+}
+
+// RateLimitRule allows Limit requests per Window to route group,
+// requests are spread evenly, so Limit is also the maximum burst
+type RateLimitRule struct {
+	// groups with different names have separate limits
+	Name   string
+	Limit  int64
+	Window time.Duration
+	Key    RateLimitKeyFunc
+}
+
+// RateLimit rejects requests over rule limit with 429,
+// limiter failures don't block requests
+func RateLimit(limiter RateLimiter, rule RateLimitRule) auth.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := rateLimitKeyPrefix + rule.Name + ":" + rule.Key(r)
+
+			res, err := limiter.TakeToken(r.Context(), key, rule.Limit, rule.Window)
+			if err != nil {
+				slog.Warn("failed to check rate limit",
+					slog.String("function", "middleware.RateLimit"),
+					slog.String("rule", rule.Name),
+					slog.String("error", err.Error()),
+				)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.FormatInt(rule.Limit, 10))
+			w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(res.Remaining, 10))
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(seconds(res.ResetAfter), 10))
+
+			if !res.Allowed {
+				w.Header().Set("Retry-After", strconv.FormatInt(seconds(res.RetryAfter), 10))
+				render.Status(r, http.StatusTooManyRequests)
+				render.JSON(w, r, response.Error("too many requests"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// seconds rounds duration up to whole seconds for headers
+func seconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -1,27 +1,58 @@
 package server
 
 import (
+	"net/http"
+	"time"
 	"tracker-backend/internal/album"
 	"tracker-backend/internal/app/dependencies"
 	"tracker-backend/internal/artist"
 	"tracker-backend/internal/auth/middleware"
 	"tracker-backend/internal/genre"
+	"tracker-backend/internal/playlist"
+	"tracker-backend/internal/search"
+	serverMiddleware "tracker-backend/internal/server/middleware"
 	"tracker-backend/internal/track"
 	"tracker-backend/internal/user"
 
 	"github.com/go-chi/chi/v5"
 )
 
+// rate limits of route groups
+var (
+	apiRateLimit = serverMiddleware.RateLimitRule{
+		Name: "api", Limit: 300, Window: time.Minute, Key: serverMiddleware.KeyByIP,
+	}
+	// login, registration and email tokens
+	authRateLimit = serverMiddleware.RateLimitRule{
+		Name: "auth", Limit: 10, Window: time.Minute, Key: serverMiddleware.KeyByIP,
+	}
+	// audio and image uploads
+	uploadRateLimit = serverMiddleware.RateLimitRule{
+		Name: "upload", Limit: 30, Window: time.Hour, Key: serverMiddleware.KeyByUser,
+	}
+)
+
 func NewAppRouter(deps *dependencies.Dependencies) *chi.Mux {
 	router := chi.NewRouter()
+	router.Use(serverMiddleware.RateLimit(deps.RateLimiter, apiRateLimit))
 	router.Get("/ping", HandlePing)
-	authMiddleware := middleware.Authorization(deps.UserService)
+	authMiddleware := middleware.Authorization(deps.UserService, deps.SessionService, deps.Config.Secret)
+	optionalAuthMiddleware := middleware.OptionalAuthorization(deps.UserService, deps.SessionService, deps.Config.Secret)
+	authRateLimitMiddleware := serverMiddleware.RateLimit(deps.RateLimiter, authRateLimit)
+	uploadMiddleware := func(next http.Handler) http.Handler {
+		return chi.Chain(
+			serverMiddleware.RateLimit(deps.RateLimiter, uploadRateLimit),
+			middleware.RequireVerifiedEmail(deps.UserVerificationService),
+		).Handler(next)
+	}
 
 	router.Get("/genres", genre.GetAllGenres)
-	user.RegisterUserRoutes(router, deps.UserService, authMiddleware)
-	artist.RegisterArtistRoutes(router, deps.ArtistService, deps.ArtistAlbumsService, authMiddleware)
-	album.RegisterAlbumRoutes(router, deps.AlbumService, deps.AlbumTracksService, authMiddleware)
-	track.RegisterTrackRoutes(router, deps.TrackService, authMiddleware)
+	user.RegisterUserRoutes(router, deps.UserService, deps.UserHistoryService, deps.UserVerificationService, authMiddleware, authRateLimitMiddleware)
+	artist.RegisterArtistRoutes(router, deps.ArtistService, deps.ArtistAlbumsService, deps.ArtistStatsService, deps.OwnershipService, authMiddleware, uploadMiddleware)
+	album.RegisterAlbumRoutes(router, deps.AlbumService, deps.AlbumTracksService, deps.AlbumModerationService, deps.AlbumRecommendationsService, deps.OwnershipService, authMiddleware, uploadMiddleware)
+	track.RegisterTrackRoutes(router, deps.TrackService, deps.OwnershipService, authMiddleware, optionalAuthMiddleware, uploadMiddleware, deps.ListenService)
+	playlist.RegisterPlaylistRoutes(router, deps.PlaylistService, deps.PlaylistTracksService, deps.PlaylistRecommendationsService, deps.OwnershipService, authMiddleware)
+	search.RegisterSearchRoutes(router, deps.SearchService, optionalAuthMiddleware)
 
 	return router
 }
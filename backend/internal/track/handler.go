@@ -1,40 +1,55 @@
 package track
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
 
 	"tracker-backend/internal/auth"
+	"tracker-backend/internal/auth/ownership"
 	genreType "tracker-backend/internal/genre/type"
+	"tracker-backend/internal/pkg/clientip"
 	uploadfile "tracker-backend/internal/pkg/file"
 	"tracker-backend/internal/pkg/response"
 	"tracker-backend/internal/pkg/service"
 )
 
 type TrackHandler struct {
-	service   *TrackService
-	validator *validator.Validate
+	service        *TrackService
+	listenRecorder ListenRecorder
+	validator      *validator.Validate
 }
 
+// ListenRecorder records listens from bytes served by stream requests
+type ListenRecorder interface {
+	RecordProgress(ctx context.Context, progress StreamProgress) error
+}
+
+// listens are recorded in background with this timeout
+const recordListenTimeout = 5 * time.Second
+
 // NewTrackHandler создает новый обработчик для треков
-func NewTrackHandler(service *TrackService) *TrackHandler {
+func NewTrackHandler(service *TrackService, listenRecorder ListenRecorder) *TrackHandler {
 	v := validator.New()
 	v.RegisterValidation("genre", genreType.ValidateGenres)
 
 	return &TrackHandler{
-		service:   service,
-		validator: v,
+		service:        service,
+		listenRecorder: listenRecorder,
+		validator:      v,
 	}
 }
 
@@ -54,17 +69,10 @@ func (h *TrackHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	// create request from form data
 	genres := strings.Split(r.FormValue("genre"), ",")
-	duration, err := strconv.Atoi(r.FormValue("duration"))
-	if err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, response.Error("failed to get duration"))
-	}
-
 	req := &CreateTrackRequest{
-		Title:    r.FormValue("title"),
-		Genre:    genres,
-		AlbumID:  r.FormValue("albumID"),
-		Duration: duration,
+		Title:   r.FormValue("title"),
+		Genre:   genres,
+		AlbumID: r.FormValue("albumID"),
 	}
 
 	// validate request
@@ -128,6 +136,43 @@ func (h *TrackHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, track.ToResponse())
 }
 
+// GetStreamURL issues signed stream url of track visible to user
+func (h *TrackHandler) GetStreamURL(w http.ResponseWriter, r *http.Request) {
+	// get context keys (user may be anonymous)
+	ctx := r.Context()
+	userID, _ := ctx.Value(auth.UserIDKey).(string)
+	userRole, _ := ctx.Value(auth.UserRoleKey).(int)
+
+	track, err := h.service.GetByID(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		if err == service.ErrNotFound {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("track not found"))
+		} else {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to get track"))
+		}
+		return
+	}
+	if !h.checkVisibility(w, r, track, userID, userRole) {
+		return
+	}
+
+	query, expiresAt := h.service.SignStreamURL(track, userID, userRole)
+
+	// urls are built from request path to keep api prefix
+	basePath := strings.TrimSuffix(r.URL.Path, "/stream-url")
+	resp := StreamURLResponse{
+		StreamURL: basePath + "/stream?" + query.Encode(),
+		ExpiresAt: expiresAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if track.HLSStatus == HLSStatusReady {
+		resp.HLSURL = basePath + "/hls/" + HLSMasterPlaylist + "?" + query.Encode()
+	}
+
+	render.JSON(w, r, resp)
+}
+
 // StreamTrack handle track stream
 func (h *TrackHandler) StreamTrack(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -138,143 +183,345 @@ func (h *TrackHandler) StreamTrack(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// get file path
-	filePath, err := h.service.GetFilePathByID(ctx, trackID)
+	// get track and open its file
+	track, err := h.service.GetByID(ctx, trackID)
 	if err != nil {
 		if err == service.ErrNotFound {
 			render.Status(r, http.StatusNotFound)
 			render.JSON(w, r, response.Error("track not found"))
 		} else {
 			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, response.Error("failed to get track file"))
+			render.JSON(w, r, response.Error("failed to get track"))
 		}
 		return
 	}
+	// user is set by signed url or authorization token
+	userID, _ := ctx.Value(auth.UserIDKey).(string)
+	userRole, _ := ctx.Value(auth.UserRoleKey).(int)
+	if !h.checkVisibility(w, r, track, userID, userRole) {
+		return
+	}
 
-	// open file
-	file, err := os.Open(filePath)
+	file, err := h.service.OpenAudio(ctx, track)
 	if err != nil {
 		render.Status(r, http.StatusBadRequest)
 		render.JSON(w, r, response.Error("failed to open track file"))
 		return
 	}
 	defer file.Close()
+	fileInfo := file.Info()
+
+	// count served bytes to record listen after response
+	ww := chiMiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+	defer h.recordListen(r, track, ww, fileInfo.Size)
+
+	// determine MIME type by extension
+	ext := strings.ToLower(filepath.Ext(track.AudioFile))
+	contentType := uploadfile.GetAudioContentTypeByExtension(ext)
+
+	// audio file names are unique per upload,
+	// so name and size identify file content
+	name := strings.TrimSuffix(track.AudioFile, filepath.Ext(track.AudioFile))
+	etag := fmt.Sprintf(`"%s-%x"`, name, fileInfo.Size)
+
+	ww.Header().Set("Content-Type", contentType)
+	ww.Header().Set("ETag", etag)
 
-	// getting info about file
-	fileInfo, err := file.Stat()
+	// ServeContent handles HEAD, single, suffix and multiple ranges,
+	// If-Range, If-None-Match and If-Modified-Since validation
+	http.ServeContent(ww, r, track.AudioFile, fileInfo.ModTime, file)
+}
+
+// GetHLSMaster serves hls master playlist of track
+func (h *TrackHandler) GetHLSMaster(w http.ResponseWriter, r *http.Request) {
+	h.serveHLS(w, r, HLSMasterPlaylist)
+}
+
+// GetHLS serves hls variant playlists and segments of track
+func (h *TrackHandler) GetHLS(w http.ResponseWriter, r *http.Request) {
+	h.serveHLS(w, r, chi.URLParam(r, "*"))
+}
+
+// serveHLS serves hls file by its name relative to track hls dir
+func (h *TrackHandler) serveHLS(w http.ResponseWriter, r *http.Request, name string) {
+	ctx := r.Context()
+	trackID := chi.URLParam(r, "id")
+
+	track, err := h.service.GetByID(ctx, trackID)
 	if err != nil {
-		render.Status(r, http.StatusBadRequest)
-		render.JSON(w, r, response.Error("failed to get file info"))
+		if err == service.ErrNotFound {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("track not found"))
+		} else {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to get track"))
+		}
+		return
+	}
+	userID, _ := ctx.Value(auth.UserIDKey).(string)
+	userRole, _ := ctx.Value(auth.UserRoleKey).(int)
+	if !h.checkVisibility(w, r, track, userID, userRole) {
+		return
+	}
+	if track.HLSStatus != HLSStatusReady {
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, response.Error("hls is not ready"))
 		return
 	}
 
-	// determine MIME type by extension
-	ext := strings.ToLower(filepath.Ext(filePath))
-	contentType := uploadfile.GetAudioContentTypeByExtension(ext)
+	file, err := h.service.OpenHLS(ctx, track, name)
+	if err != nil {
+		if err == service.ErrNotFound {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("hls file not found"))
+		} else {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("failed to open hls file"))
+		}
+		return
+	}
+	defer file.Close()
 
-	// set necessary headers
-	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Length", strconv.FormatInt(fileInfo.Size(), 10))
-	w.Header().Set("Accept-Ranges", "bytes")
+	ext := filepath.Ext(name)
+	w.Header().Set("Content-Type", uploadfile.GetHLSContentTypeByExtension(ext))
+	if ext != ".m3u8" {
+		// served segments are counted as played time of track
+		ww := chiMiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		defer h.recordHLSListen(r, track, name, ww)
 
-	// handling Range Requests to Support Partial Loading
-	rangeHeader := r.Header.Get("Range")
-	if rangeHeader != "" {
-		h.handleRangeRequest(w, r, file, fileInfo.Size(), rangeHeader)
+		http.ServeContent(ww, r, name, file.Info().ModTime, file)
 		return
 	}
 
-	// send whole file if range is not defined
-	w.WriteHeader(http.StatusOK)
-	_, err = io.Copy(w, file)
+	// playlist uris get signature of request,
+	// so player requests of variants and segments are signed too
+	playlist, err := io.ReadAll(file)
 	if err != nil {
-		// log the error, but do not send the response
-		// because the headers have already been sent.
-		slog.Error("error streaming file", slog.String("error", err.Error()))
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("failed to read hls playlist"))
+		return
+	}
+	signed := signPlaylist(playlist, r.URL.RawQuery)
+	http.ServeContent(w, r, name, file.Info().ModTime, bytes.NewReader(signed))
+}
+
+// checkVisibility writes error response if album of track is not visible to user
+func (h *TrackHandler) checkVisibility(
+	w http.ResponseWriter, r *http.Request, track *Track, userID string, userRole int,
+) bool {
+	err := h.service.CheckVisibility(r.Context(), track, userID, userRole)
+	if err == nil {
+		return true
 	}
+
+	if errors.Is(err, service.ErrAccessDenied) {
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, response.Error("track is not available"))
+	} else {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error(err.Error()))
+	}
+	return false
 }
 
+// signPlaylist appends query to uri lines of m3u8 playlist
+func signPlaylist(playlist []byte, rawQuery string) []byte {
+	if rawQuery == "" {
+		return playlist
+	}
+
+	lines := strings.Split(string(playlist), "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines[i] = line + "?" + rawQuery
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// Update updates track metadata and optionally replaces audio file
 func (h *TrackHandler) Update(w http.ResponseWriter, r *http.Request) {
-	// update name, genre or file
-	// if file updated - delete old one
-	render.Status(r, http.StatusNotImplemented)
+	ctx := r.Context()
+
+	// get track loaded by ownership middleware
+	var track Track
+	if err := ownership.ResourceFromContext(ctx, &track); err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error("failed to get track"))
+		return
+	}
+
+	// parse multipart form
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("failed to parse multipart form"))
+		return
+	}
+
+	// create request from form data
+	req := &UpdateTrackRequest{}
+	if r.PostForm.Has("title") {
+		title := r.PostForm.Get("title")
+		req.Title = &title
+	}
+	if r.PostForm.Has("genre") {
+		req.Genre = strings.Split(r.PostForm.Get("genre"), ",")
+	}
+
+	// validate request
+	if err := h.validator.Struct(req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r,
+			response.ValidationErrorsResp(err.(validator.ValidationErrors)),
+		)
+		return
+	}
+
+	// get optional audio file
+	audioFile, fileHeader, err := r.FormFile("audio")
+	if err != nil && !errors.Is(err, http.ErrMissingFile) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("failed to get audio file"))
+		return
+	}
+	if audioFile != nil {
+		defer audioFile.Close()
+	}
+
+	// update track document and replace file
+	updated, err := h.service.Update(ctx, &track, req, &audioFile, fileHeader)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTitleTaken), errors.Is(err, ErrAudioReplaced):
+			render.Status(r, http.StatusConflict)
+		case errors.Is(err, service.ErrNotFound):
+			render.Status(r, http.StatusNotFound)
+		default:
+			render.Status(r, http.StatusBadRequest)
+		}
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
+
+	render.JSON(w, r, updated.ToResponse())
 }
 
 func (h *TrackHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	userID := ctx.Value(auth.UserIDKey).(string)
-	trackID := chi.URLParam(r, "id")
 
-	err := h.service.Delete(ctx, trackID, userID)
+	// get track loaded by ownership middleware
+	var track Track
+	if err := ownership.ResourceFromContext(ctx, &track); err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error("failed to get track"))
+		return
+	}
+
+	err := h.service.Delete(ctx, &track)
 	if err != nil {
 		render.Status(r, http.StatusBadRequest)
 		render.JSON(w, r, response.Error(err.Error()))
+		return
 	}
 
 	render.Status(r, http.StatusNoContent)
 }
 
-// handleRangeRequest обрабатывает запросы с заголовком Range
-func (h *TrackHandler) handleRangeRequest(w http.ResponseWriter, r *http.Request, file *os.File, fileSize int64, rangeHeader string) {
-	// parse Range header (example: "bytes=0-1023")
-	if !strings.HasPrefix(rangeHeader, "bytes=") {
-		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+// recordListen passes served part of track to listen recorder
+// without blocking response
+func (h *TrackHandler) recordListen(
+	r *http.Request, track *Track, ww chiMiddleware.WrapResponseWriter, fileSize int64,
+) {
+	if r.Method == http.MethodHead {
 		return
 	}
 
-	ranges := strings.TrimPrefix(rangeHeader, "bytes=")
-	rangeParts := strings.Split(ranges, "-")
-
-	if len(rangeParts) != 2 {
-		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+	var start int64
+	switch ww.Status() {
+	case http.StatusOK:
+	case http.StatusPartialContent:
+		// response header looks like "bytes 100-200/1000",
+		// multipart responses have no Content-Range header and are skipped
+		contentRange := strings.TrimPrefix(ww.Header().Get("Content-Range"), "bytes ")
+		first, _, _ := strings.Cut(contentRange, "-")
+		var err error
+		if start, err = strconv.ParseInt(first, 10, 64); err != nil {
+			return
+		}
+	default:
 		return
 	}
 
-	var start, end int64
-	var err error
-
-	// parse start byte position
-	if rangeParts[0] != "" {
-		start, err = strconv.ParseInt(rangeParts[0], 10, 64)
-		if err != nil {
-			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
-			return
-		}
+	progress := StreamProgress{
+		Track:    track,
+		Start:    start,
+		Served:   int64(ww.BytesWritten()),
+		FileSize: fileSize,
 	}
+	progress.UserID, progress.SessionKey = listenSession(r)
+	h.record(progress)
+}
 
-	// parse end byte position
-	if rangeParts[1] != "" {
-		end, err = strconv.ParseInt(rangeParts[1], 10, 64)
-		if err != nil {
-			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
-			return
-		}
-	} else {
-		end = fileSize - 1
+// recordHLSListen passes played time of served hls segment to listen recorder.
+// Segment "128k_003.ts" covers seconds from 3*HLSSegmentDuration of track
+func (h *TrackHandler) recordHLSListen(
+	r *http.Request, track *Track, name string, ww chiMiddleware.WrapResponseWriter,
+) {
+	if r.Method == http.MethodHead || ww.Status() != http.StatusOK || track.Duration <= 0 {
+		return
 	}
 
-	// check correction of Range
-	if start < 0 || end >= fileSize || start > end {
-		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+	_, number, ok := strings.Cut(strings.TrimSuffix(name, filepath.Ext(name)), "_")
+	if !ok {
+		return
+	}
+	index, err := strconv.Atoi(number)
+	if err != nil || index < 0 {
 		return
 	}
 
-	// set read position on file
-	_, err = file.Seek(start, 0)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	duration := int64(track.Duration)
+	start := int64(index) * HLSSegmentDuration
+	if start >= duration {
 		return
 	}
 
-	// set headers
-	contentLength := end - start + 1
-	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, fileSize))
-	w.Header().Set("Content-Length", strconv.FormatInt(contentLength, 10))
-	w.WriteHeader(http.StatusPartialContent)
+	progress := StreamProgress{
+		Track:    track,
+		Start:    start,
+		Served:   min(HLSSegmentDuration, duration-start),
+		FileSize: duration,
+	}
+	progress.UserID, progress.SessionKey = listenSession(r)
+	// seconds of hls session are not summed up with bytes of stream session
+	progress.SessionKey = "hls:" + progress.SessionKey
+	h.record(progress)
+}
 
-	// send the requested part of the file
-	_, err = io.CopyN(w, file, contentLength)
-	if err != nil {
-		fmt.Printf("Error streaming partial file: %v\n", err)
+// listenSession returns user and listening session of request
+func listenSession(r *http.Request) (userID, sessionKey string) {
+	if userID, ok := r.Context().Value(auth.UserIDKey).(string); ok {
+		return userID, userID
 	}
+
+	// anonymous session is identified by client address
+	return "", "anonymous:" + clientip.FromRequest(r)
+}
+
+// record passes progress to listen recorder in background
+func (h *TrackHandler) record(progress StreamProgress) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), recordListenTimeout)
+		defer cancel()
+
+		if err := h.listenRecorder.RecordProgress(ctx, progress); err != nil {
+			slog.Warn("failed to record listen",
+				slog.String("function", "track.TrackHandler.record"),
+				slog.String("trackID", progress.Track.ID),
+				slog.String("error", err.Error()),
+			)
+		}
+	}()
 }
@@ -2,32 +2,67 @@ package track
 
 // CreateTrackRequest represents a request to create a new track
 type CreateTrackRequest struct {
-	Title    string   `json:"title" validate:"required,min=1,max=128"`
-	Genre    []string `json:"genre" validate:"required,genre"`
-	Duration int      `json:"duration" validate:"required,min=10"`
-	AlbumID  string   `json:"albumID" validate:"required,uuid4"`
+	Title   string   `json:"title" validate:"required,min=1,max=128"`
+	Genre   []string `json:"genre" validate:"required,genre"`
+	AlbumID string   `json:"albumID" validate:"required,uuid4"`
+}
+
+// UpdateTrackRequest represents a request to update track metadata
+// nil fields are not updated
+type UpdateTrackRequest struct {
+	Title *string  `json:"title" validate:"omitempty,min=1,max=128"`
+	Genre []string `json:"genre" validate:"omitempty,genre"`
 }
 
 // TrackResponse represents a response with track information
 type TrackResponse struct {
-	ID        string   `json:"id"`
-	Title     string   `json:"title"`
-	Duration  int      `json:"duration"`
-	Genre     []string `json:"genre"`
-	AudioFile string   `json:"audioFile"`
-	AlbumID   string   `json:"albumID"`
-	CreatedAt string   `json:"createdAt"`
+	ID         string   `json:"id"`
+	Title      string   `json:"title"`
+	Duration   int      `json:"duration"`
+	SampleRate int      `json:"sampleRate"`
+	Channels   int      `json:"channels"`
+	Bitrate    int      `json:"bitrate"`
+	Genre      []string `json:"genre"`
+	AudioFile  string   `json:"audioFile"`
+	AlbumID    string   `json:"albumID"`
+	PlayCount  int64    `json:"playCount"`
+	HLSStatus  string   `json:"hlsStatus"`
+	CreatedAt  string   `json:"createdAt"`
 }
 
 // ToResponse converts Track to TrackResponse
 func (t *Track) ToResponse() TrackResponse {
 	return TrackResponse{
-		ID:        t.ID,
-		Title:     t.Title,
-		Duration:  t.Duration,
-		Genre:     t.Genre,
-		AudioFile: t.AudioFile,
-		AlbumID:   t.AlbumID,
-		CreatedAt: t.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		ID:         t.ID,
+		Title:      t.Title,
+		Duration:   t.Duration,
+		SampleRate: t.SampleRate,
+		Channels:   t.Channels,
+		Bitrate:    t.Bitrate,
+		Genre:      t.Genre,
+		AudioFile:  t.AudioFile,
+		AlbumID:    t.AlbumID,
+		PlayCount:  t.PlayCount,
+		HLSStatus:  t.HLSStatus,
+		CreatedAt:  t.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
 }
+
+// StreamURLResponse represents signed urls of track stream
+type StreamURLResponse struct {
+	StreamURL string `json:"streamURL"`
+	HLSURL    string `json:"hlsURL,omitempty"` // set when hls is ready
+	ExpiresAt string `json:"expiresAt"`
+}
+
+// StreamProgress describes part of track served by one stream request.
+// Stream progress is measured in bytes of audio file,
+// hls progress is measured in seconds of segments
+type StreamProgress struct {
+	UserID     string // empty for anonymous listeners
+	SessionKey string // user id or client address of anonymous listener
+	Track      *Track
+	Start      int64 // first served byte or second
+	Served     int64 // number of served bytes or seconds
+	FileSize   int64 // file size or duration
+}
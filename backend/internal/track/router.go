@@ -1,20 +1,55 @@
 package track
 
 import (
+	"net/http"
 	"tracker-backend/internal/auth"
+	"tracker-backend/internal/auth/middleware"
+	"tracker-backend/internal/auth/ownership"
 
 	"github.com/go-chi/chi/v5"
 )
 
-func RegisterTrackRoutes(r chi.Router, s *TrackService, authMiddleware auth.MiddlewareFunc) {
-	h := NewTrackHandler(s)
+func RegisterTrackRoutes(
+	r chi.Router,
+	s *TrackService,
+	ownershipSvc *ownership.OwnershipService,
+	authMiddleware auth.MiddlewareFunc,
+	optionalAuthMiddleware auth.MiddlewareFunc,
+	uploadMiddleware auth.MiddlewareFunc,
+	listenRecorder ListenRecorder,
+) {
+	h := NewTrackHandler(s, listenRecorder)
 
 	r.Route("/track", func(r chi.Router) {
 		r.Group(func(rm chi.Router) {
 			rm.Use(authMiddleware)
-			rm.Post("/", h.Create)
+			rm.With(uploadMiddleware).Post("/", h.Create)
+
+			// owner only
+			rm.With(ownershipSvc.Require(ownership.ResourceTrack, ownership.NoBypass), uploadMiddleware).
+				Put("/{id}", h.Update)
+
+			// owner or admin
+			rm.With(ownershipSvc.Require(ownership.ResourceTrack, auth.RoleAdmin)).
+				Delete("/{id}", h.Delete)
+		})
+		r.With(optionalAuthMiddleware).Get("/{id}/stream-url", h.GetStreamURL)
+
+		// audio is served by signed urls only
+		r.Group(func(rs chi.Router) {
+			rs.Use(optionalAuthMiddleware, middleware.RequireSignedURL(s.secret, streamResource))
+
+			rs.Get("/{id}/stream", h.StreamTrack)
+			rs.Head("/{id}/stream", h.StreamTrack)
+
+			rs.Get("/{id}/hls/"+HLSMasterPlaylist, h.GetHLSMaster)
+			rs.Get("/{id}/hls/*", h.GetHLS)
 		})
-		r.Get("/{id}/stream", h.StreamTrack)
 		r.Get("/{id}", h.GetByID)
 	})
 }
+
+// streamResource returns signed resource of requested track
+func streamResource(r *http.Request) string {
+	return StreamResource(chi.URLParam(r, "id"))
+}
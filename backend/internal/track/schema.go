@@ -2,7 +2,10 @@ package track
 
 import (
 	"context"
+	"path"
+	"strings"
 	"time"
+	"tracker-backend/internal/config"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
@@ -11,13 +14,47 @@ import (
 
 // Track represents a music track in the system
 type Track struct {
-	ID        string    `bson:"id"`
-	Title     string    `bson:"title"`
-	Duration  int       `bson:"duration"` // duration in seconds
-	Genre     []string  `bson:"genre"`
-	AudioFile string    `bson:"audioFile"`
-	AlbumID   string    `bson:"album"`
-	CreatedAt time.Time `bson:"createdAt"`
+	ID         string    `bson:"id"`
+	Title      string    `bson:"title"`
+	Duration   int       `bson:"duration"`   // duration in seconds
+	SampleRate int       `bson:"sampleRate"` // samples per second
+	Channels   int       `bson:"channels"`
+	Bitrate    int       `bson:"bitrate"` // bits per second
+	Genre      []string  `bson:"genre"`
+	AudioFile  string    `bson:"audioFile"`
+	AlbumID    string    `bson:"album"`
+	PlayCount  int64     `bson:"playCount"`
+	CreatedAt  time.Time `bson:"createdAt"`
+
+	// hls transcoding state, tracks uploaded before hls have no status
+	HLSStatus    string    `bson:"hlsStatus,omitempty"`
+	HLSAttempts  int       `bson:"hlsAttempts"`
+	HLSUpdatedAt time.Time `bson:"hlsUpdatedAt,omitempty"`
+}
+
+// hls transcoding statuses
+const (
+	HLSStatusPending    = "pending"
+	HLSStatusProcessing = "processing"
+	HLSStatusReady      = "ready"
+	HLSStatusFailed     = "failed"
+)
+
+// HLSMasterPlaylist is a name of hls master playlist in track hls dir
+const HLSMasterPlaylist = "master.m3u8"
+
+// HLSSegmentDuration is a target duration of hls segments in seconds
+const HLSSegmentDuration = 6
+
+// AudioKey returns blob key of track audio file
+func (t *Track) AudioKey() string {
+	return path.Join(config.AudioDir, t.AudioFile)
+}
+
+// HLSDir returns blob key prefix of hls files,
+// it depends on audio file so replaced audio gets new playlists
+func (t *Track) HLSDir() string {
+	return path.Join(config.HLSDir, strings.TrimSuffix(t.AudioFile, path.Ext(t.AudioFile)))
 }
 
 func EnsureIndexes(ctx context.Context, col *mongo.Collection) error {
@@ -36,6 +73,21 @@ func EnsureIndexes(ctx context.Context, col *mongo.Collection) error {
 		Options: options.Index().SetUnique(true).SetName("id_unique"),
 	}
 
-	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{nameAlbumIndex, idIndex})
+	// text index for search by title
+	titleTextIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "title", Value: "text"}},
+		Options: options.Index().SetName("title_text"),
+	}
+
+	// index for picking tracks waiting for hls transcoding
+	hlsStatusIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "hlsStatus", Value: 1},
+			{Key: "hlsUpdatedAt", Value: 1},
+		},
+		Options: options.Index().SetName("hlsStatus_hlsUpdatedAt"),
+	}
+
+	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{nameAlbumIndex, idIndex, titleTextIndex, hlsStatusIndex})
 	return err
 }
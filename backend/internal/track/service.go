@@ -6,28 +6,49 @@ import (
 	"fmt"
 	"log/slog"
 	"mime/multipart"
-	"os"
+	"net/url"
 	"path"
 	"path/filepath"
+	"strings"
 	"time"
 	"tracker-backend/internal/auth/ownership"
 	"tracker-backend/internal/config"
+	"tracker-backend/internal/pkg/audioprobe"
 	uploadfile "tracker-backend/internal/pkg/file"
 	"tracker-backend/internal/pkg/service"
+	"tracker-backend/internal/pkg/signedurl"
+	"tracker-backend/internal/pkg/storage"
 
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
 type TrackService struct {
 	Col              *mongo.Collection
 	ownershipService *ownership.OwnershipService
+	blobStore        storage.BlobStore
+	// secret of signed stream urls
+	secret string
 	AlbumChecker
 }
 
+var (
+	ErrTitleTaken      = errors.New("track with this title already exists in album")
+	ErrNothingToUpdate = errors.New("nothing to update")
+	ErrTrackTooShort   = errors.New("track must be at least 10 seconds long")
+	ErrAudioReplaced   = errors.New("track audio was replaced concurrently")
+)
+
+const minTrackDuration = 10 * time.Second
+
+// lifetime of signed stream url in addition to track duration
+const streamURLTTL = 15 * time.Minute
+
 type AlbumChecker interface {
 	CheckExistence(ctx context.Context, albumID string) (bool, error)
+	IsVisible(ctx context.Context, albumID, userID string, userRole int) (bool, error)
 }
 
 // NewService creates new service for tracks
@@ -35,11 +56,15 @@ func NewTrackService(
 	tracksCollection *mongo.Collection,
 	ownershipService *ownership.OwnershipService,
 	albumChecker AlbumChecker,
+	blobStore storage.BlobStore,
+	secret string,
 ) *TrackService {
 	return &TrackService{
 		Col:              tracksCollection,
 		AlbumChecker:     albumChecker,
 		ownershipService: ownershipService,
+		blobStore:        blobStore,
+		secret:           secret,
 	}
 }
 
@@ -71,18 +96,22 @@ func (s *TrackService) Create(
 		return nil, service.ErrAccessDenied
 	}
 
-	// check file type
+	// check file type and content
 	if err := uploadfile.ValidateFile(fileHeader, uploadfile.AllowedAudioExtensions); err != nil {
 		return nil, err
 	}
+	audioInfo, err := probeAudio(*audioFile, fileHeader)
+	if err != nil {
+		return nil, err
+	}
 
-	// create file path
-	filePath := path.Join(os.Getenv(config.PublicDirPathEnvName), config.AudioDir)
 	// upload file
-	audioFilePath, err := uploadfile.UploadFile(
+	audioKey, err := uploadfile.UploadFile(
+		ctx,
+		s.blobStore,
 		fileHeader,
 		audioFile,
-		filePath,
+		config.AudioDir,
 		uploadfile.AllowedAudioExtensions,
 	)
 	if err != nil {
@@ -90,15 +119,22 @@ func (s *TrackService) Create(
 		return nil, err
 	}
 
-	// create new document
+	// create new document, hls files are made by transcoder job
+	now := time.Now()
 	track := &Track{
-		ID:        uuid.NewString(),
-		Title:     req.Title,
-		Genre:     req.Genre,
-		Duration:  req.Duration,
-		AudioFile: filepath.Base(audioFilePath),
-		AlbumID:   req.AlbumID,
-		CreatedAt: time.Now(),
+		ID:         uuid.NewString(),
+		Title:      req.Title,
+		Genre:      req.Genre,
+		Duration:   int(audioInfo.Duration.Round(time.Second).Seconds()),
+		SampleRate: audioInfo.SampleRate,
+		Channels:   audioInfo.Channels,
+		Bitrate:    audioInfo.Bitrate,
+		AudioFile:  path.Base(audioKey),
+		AlbumID:    req.AlbumID,
+		CreatedAt:  now,
+
+		HLSStatus:    HLSStatusPending,
+		HLSUpdatedAt: now,
 	}
 
 	// insert to collection
@@ -106,7 +142,7 @@ func (s *TrackService) Create(
 	if err != nil {
 		logger.Error("failed to insert", slog.String("error", err.Error()))
 		// delete related file if error occurred
-		os.Remove(filePath)
+		s.blobStore.Delete(ctx, audioKey)
 		return nil, service.ErrUploadFailed
 	}
 
@@ -114,7 +150,7 @@ func (s *TrackService) Create(
 		slog.Group("info",
 			slog.String("albumID", req.AlbumID),
 			slog.String("id", track.ID),
-			slog.String("audioPath", audioFilePath),
+			slog.String("audioKey", audioKey),
 		),
 	)
 
@@ -141,64 +177,228 @@ func (s *TrackService) GetByID(
 	return &track, nil
 }
 
-// GetFilePathByID returns full file path to track
-func (s *TrackService) GetFilePathByID(ctx context.Context, id string) (string, error) {
+// CheckVisibility checks that album of track is visible to user,
+// empty user id is used for anonymous user
+func (s *TrackService) CheckVisibility(
+	ctx context.Context, track *Track, userID string, userRole int,
+) error {
 	// configure logger
-	logger := slog.With(slog.String("function", "track.TrackService.GetFilePathByID"))
+	logger := slog.With(slog.String("function", "track.TrackService.CheckVisibility"))
 
-	// get track
-	track, err := s.GetByID(ctx, id)
+	visible, err := s.AlbumChecker.IsVisible(ctx, track.AlbumID, userID, userRole)
 	if err != nil {
-		return "", err
+		logger.Warn("failed to check album visibility", slog.String("error", err.Error()))
+		return errors.New("failed to check album visibility")
+	}
+	if !visible {
+		return service.ErrAccessDenied
 	}
+	return nil
+}
 
-	// define filepath
-	filePath := filepath.Join(
-		os.Getenv(config.PublicDirPathEnvName),
-		config.AudioDir,
-		track.AudioFile,
+// SignStreamURL returns query params of stream url signed for user
+func (s *TrackService) SignStreamURL(track *Track, userID string, userRole int) (url.Values, time.Time) {
+	// url stays valid while track is played and seeked
+	expiresAt := time.Now().Add(time.Duration(track.Duration)*time.Second + streamURLTTL)
+	query := signedurl.Sign(
+		s.secret,
+		StreamResource(track.ID),
+		signedurl.Signer{UserID: userID, UserRole: userRole},
+		expiresAt,
 	)
+	return query, expiresAt
+}
 
-	// check file existence
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		logger.Warn("failed to get track file", slog.String("error", err.Error()))
-		return "", errors.New("audio file not found")
+// StreamResource returns name of signed resource of track stream,
+// the same signature grants access to stream and hls files
+func StreamResource(trackID string) string {
+	return "track:" + trackID
+}
+
+// OpenAudio opens audio file of track, caller must close it
+func (s *TrackService) OpenAudio(ctx context.Context, track *Track) (storage.Blob, error) {
+	// configure logger
+	logger := slog.With(slog.String("function", "track.TrackService.OpenAudio"))
+
+	blob, err := s.blobStore.Open(ctx, track.AudioKey())
+	if err != nil {
+		logger.Warn("failed to open track file", slog.String("error", err.Error()))
+		return nil, errors.New("audio file not found")
 	}
 
-	return filePath, nil
+	return blob, nil
 }
 
-// Delete removes track by id
-func (s *TrackService) Delete(
-	ctx context.Context, id string, userID string,
-) error {
+// OpenHLS opens hls playlist or segment of track by its name
+// relative to track hls dir, caller must close it
+func (s *TrackService) OpenHLS(ctx context.Context, track *Track, name string) (storage.Blob, error) {
 	// configure logger
-	logger := slog.With(slog.String("function", "track.TrackService.Delete"))
+	logger := slog.With(slog.String("function", "track.TrackService.OpenHLS"))
+
+	dir := track.HLSDir()
+	key := path.Join(dir, name)
+	if !strings.HasPrefix(key, dir+"/") {
+		return nil, service.ErrNotFound
+	}
 
-	// check ownership
-	isOwner, err := s.ownershipService.IsTrackOwner(ctx, id, userID)
+	blob, err := s.blobStore.Open(ctx, key)
 	if err != nil {
-		return errors.New("failed to check ownership")
+		if errors.Is(err, storage.ErrBlobNotFound) || errors.Is(err, storage.ErrInvalidBlobKey) {
+			return nil, service.ErrNotFound
+		}
+		logger.Warn("failed to open hls file", slog.String("error", err.Error()))
+		return nil, errors.New("failed to open hls file")
 	}
-	if !isOwner {
-		return service.ErrAccessDenied
+
+	return blob, nil
+}
+
+// removeHLS deletes all hls files of track audio
+func (s *TrackService) removeHLS(ctx context.Context, track *Track) error {
+	blobs, err := s.blobStore.List(ctx, track.HLSDir()+"/")
+	if err != nil {
+		return err
+	}
+	for _, blob := range blobs {
+		if err := s.blobStore.Delete(ctx, blob.Key); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	// find document
-	var track Track
-	if err := s.Col.FindOne(ctx, bson.M{"id": id}).Decode(&track); err != nil {
-		return service.ErrNotFound
+// Update updates track metadata and optionally replaces audio file
+// old audio file is deleted only after document is updated
+// track is loaded by ownership middleware
+func (s *TrackService) Update(
+	ctx context.Context,
+	track *Track,
+	req *UpdateTrackRequest,
+	audioFile *multipart.File,
+	fileHeader *multipart.FileHeader,
+) (*Track, error) {
+	// configure logger
+	logger := slog.With(slog.String("function", "track.TrackService.Update"))
+
+	updates := bson.M{}
+	if req.Title != nil {
+		updates["title"] = *req.Title
+	}
+	if len(req.Genre) > 0 {
+		updates["genre"] = req.Genre
+	}
+
+	// upload new audio file
+	newAudioKey := ""
+	if fileHeader != nil {
+		if err := uploadfile.ValidateFile(fileHeader, uploadfile.AllowedAudioExtensions); err != nil {
+			return nil, err
+		}
+		audioInfo, err := probeAudio(*audioFile, fileHeader)
+		if err != nil {
+			return nil, err
+		}
+		uploaded, err := uploadfile.UploadFile(
+			ctx,
+			s.blobStore,
+			fileHeader,
+			audioFile,
+			config.AudioDir,
+			uploadfile.AllowedAudioExtensions,
+		)
+		if err != nil {
+			logger.Error("failed to upload file", slog.String("error", err.Error()))
+			return nil, err
+		}
+		newAudioKey = uploaded
+		updates["audioFile"] = path.Base(uploaded)
+		updates["duration"] = int(audioInfo.Duration.Round(time.Second).Seconds())
+		updates["sampleRate"] = audioInfo.SampleRate
+		updates["channels"] = audioInfo.Channels
+		updates["bitrate"] = audioInfo.Bitrate
+		// new audio must be transcoded again
+		updates["hlsStatus"] = HLSStatusPending
+		updates["hlsAttempts"] = 0
+		updates["hlsUpdatedAt"] = time.Now()
 	}
 
+	if len(updates) == 0 {
+		return nil, ErrNothingToUpdate
+	}
+
+	// audio is swapped only if it wasn't replaced since track was loaded,
+	// so concurrent replacements can't delete the same old file
+	filter := bson.M{"id": track.ID}
+	if newAudioKey != "" {
+		filter["audioFile"] = track.AudioFile
+	}
+
+	// update document
+	var updated Track
+	err := s.Col.FindOneAndUpdate(ctx,
+		filter,
+		bson.M{"$set": updates},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+
+	if err != nil {
+		logger.Warn("failed to update document", slog.String("error", err.Error()))
+		// new file is not referenced - delete it
+		if newAudioKey != "" {
+			s.blobStore.Delete(ctx, newAudioKey)
+		}
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			if newAudioKey == "" {
+				return nil, service.ErrNotFound
+			}
+			count, err := s.Col.CountDocuments(ctx, bson.M{"id": track.ID})
+			if err != nil {
+				return nil, errors.New("failed to check track existence")
+			}
+			if count < 1 {
+				return nil, service.ErrNotFound
+			}
+			return nil, ErrAudioReplaced
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrTitleTaken
+		}
+		return nil, errors.New("failed to update track")
+	}
+
+	// delete replaced audio file and its hls files
+	if newAudioKey != "" {
+		if err := s.blobStore.Delete(ctx, track.AudioKey()); err != nil {
+			logger.Warn("failed to remove old audio file", slog.String("error", err.Error()))
+		}
+		if err := s.removeHLS(ctx, track); err != nil {
+			logger.Warn("failed to remove old hls files", slog.String("error", err.Error()))
+		}
+	}
+
+	return &updated, nil
+}
+
+// Delete removes track and its audio file
+// track is loaded by ownership middleware
+func (s *TrackService) Delete(
+	ctx context.Context, track *Track,
+) error {
+	// configure logger
+	logger := slog.With(slog.String("function", "track.TrackService.Delete"))
+
 	// remove track
-	filePath := filepath.Join(os.Getenv(config.PublicDirPathEnvName), config.AudioDir, track.AudioFile)
-	if err := os.Remove(filePath); err != nil {
+	if err := s.blobStore.Delete(ctx, track.AudioKey()); err != nil {
 		logger.Warn("failed to remove file", slog.String("error", err.Error()))
 		return errors.New("failed to delete audio")
 	}
+	// hls files left on failure are collected by media gc
+	if err := s.removeHLS(ctx, track); err != nil {
+		logger.Warn("failed to remove hls files", slog.String("error", err.Error()))
+	}
 
 	// remove document
-	res, err := s.Col.DeleteOne(ctx, bson.M{"id": id})
+	res, err := s.Col.DeleteOne(ctx, bson.M{"id": track.ID})
 	if err != nil {
 		logger.Warn("failed to delete document", slog.String("error", err.Error()))
 		return errors.New("failed to remove")
@@ -209,3 +409,26 @@ func (s *TrackService) Delete(
 
 	return nil
 }
+
+// probeAudio checks that audio content matches file extension
+// and reads its technical parameters
+func probeAudio(
+	audioFile multipart.File, fileHeader *multipart.FileHeader,
+) (*audioprobe.Info, error) {
+	ext := filepath.Ext(fileHeader.Filename)
+	info, err := audioprobe.ProbeWithExtension(audioFile, fileHeader.Size, ext)
+	if err != nil {
+		slog.Info("audio file rejected",
+			slog.String("function", "track.probeAudio"),
+			slog.String("filename", fileHeader.Filename),
+			slog.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	if info.Duration < minTrackDuration {
+		return nil, ErrTrackTooShort
+	}
+
+	return info, nil
+}
@@ -0,0 +1,63 @@
+package track
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"mime/multipart"
+	"testing"
+	"tracker-backend/internal/pkg/audioprobe"
+)
+
+// memFile is an uploaded file kept in memory
+type memFile struct {
+	*bytes.Reader
+}
+
+func (memFile) Close() error { return nil }
+
+// pcmWAV returns 8 kHz mono 8 bit wav file, one byte per sample
+func pcmWAV(samples int) []byte {
+	buf := make([]byte, 44+samples)
+	copy(buf[0:], "RIFF")
+	binary.LittleEndian.PutUint32(buf[4:], uint32(36+samples))
+	copy(buf[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(buf[16:], 16)
+	binary.LittleEndian.PutUint16(buf[20:], 1) // PCM
+	binary.LittleEndian.PutUint16(buf[22:], 1) // channels
+	binary.LittleEndian.PutUint32(buf[24:], 8000)
+	binary.LittleEndian.PutUint32(buf[28:], 8000) // byte rate
+	binary.LittleEndian.PutUint16(buf[32:], 1)    // block align
+	binary.LittleEndian.PutUint16(buf[34:], 8)    // bits per sample
+	copy(buf[36:], "data")
+	binary.LittleEndian.PutUint32(buf[40:], uint32(samples))
+	return buf
+}
+
+func TestProbeAudioMinDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		data     []byte
+		err      error
+	}{
+		{name: "min duration", filename: "track.wav", data: pcmWAV(80000)},
+		{name: "longer than min", filename: "track.wav", data: pcmWAV(80001)},
+		{name: "one sample short", filename: "track.wav", data: pcmWAV(79999), err: ErrTrackTooShort},
+		{name: "empty", filename: "track.wav", data: pcmWAV(0), err: audioprobe.ErrMalformed},
+		{name: "extension mismatch", filename: "track.mp3", data: pcmWAV(80000), err: audioprobe.ErrFormatMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := &multipart.FileHeader{Filename: tt.filename, Size: int64(len(tt.data))}
+			info, err := probeAudio(memFile{bytes.NewReader(tt.data)}, header)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("probeAudio error = %v, want %v", err, tt.err)
+			}
+			if tt.err == nil && info.Duration < minTrackDuration {
+				t.Fatalf("duration = %s, want at least %s", info.Duration, minTrackDuration)
+			}
+		})
+	}
+}
@@ -2,12 +2,21 @@ package user
 
 import (
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 	"tracker-backend/internal/auth"
+	"tracker-backend/internal/auth/lockout"
+	"tracker-backend/internal/auth/session"
+	"tracker-backend/internal/pkg/clientip"
+	"tracker-backend/internal/pkg/pagination"
 	"tracker-backend/internal/pkg/response"
 	"tracker-backend/internal/pkg/service"
 	userType "tracker-backend/internal/user/type"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
 )
@@ -68,18 +77,88 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.Service.Login(r.Context(), req)
+	tokens, err := h.Service.Login(r.Context(), req, clientip.FromRequest(r))
 	if err != nil {
-		if errors.Is(err, service.ErrNotFound) {
+		var lockErr *lockout.LockedError
+		if errors.As(err, &lockErr) {
+			w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(lockErr.RetryAfter.Seconds())), 10))
+			render.Status(r, http.StatusTooManyRequests)
+		} else if errors.Is(err, service.ErrNotFound) {
 			render.Status(r, http.StatusNotFound)
 		} else if errors.Is(err, service.ErrAccessDenied) {
 			render.Status(r, http.StatusForbidden)
+		} else {
+			render.Status(r, http.StatusInternalServerError)
+		}
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
+
+	render.JSON(w, r, tokenResponse(tokens))
+}
+
+// POST /user/refresh
+func (h *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req userType.RefreshRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid request body"))
+		return
+	}
+
+	if err := h.Validator.Struct(req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.ValidationErrorsResp(err.(validator.ValidationErrors)))
+		return
+	}
+
+	tokens, err := h.Service.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, session.ErrInvalidRefreshToken) {
+			render.Status(r, http.StatusUnauthorized)
+		} else {
+			render.Status(r, http.StatusInternalServerError)
 		}
 		render.JSON(w, r, response.Error(err.Error()))
 		return
 	}
 
-	render.JSON(w, r, map[string]string{"token": token})
+	render.JSON(w, r, tokenResponse(tokens))
+}
+
+// POST /user/logout
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(string)
+	sessionID := r.Context().Value(auth.SessionIDKey).(string)
+
+	if err := h.Service.Logout(r.Context(), userID, sessionID); err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /user/logout/all
+func (h *UserHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(string)
+
+	if err := h.Service.LogoutAll(r.Context(), userID); err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func tokenResponse(tokens *session.TokenPair) *userType.TokenResponse {
+	return &userType.TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    int64(time.Until(tokens.ExpiresAt).Seconds()),
+	}
 }
 
 // GET /user
@@ -114,23 +193,13 @@ func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// get 'Allow-Access' header
-	aaHeader := r.Header.Get("Allow-Access")
-	allowed := false
-	if aaHeader == "1" {
-		allowed = true
-	}
-
 	// check update results
-	user, err := h.Service.Update(r.Context(), userID, req, allowed)
+	user, err := h.Service.Update(r.Context(), userID, req)
 	if err != nil {
-		if errors.Is(err, service.ErrAccessDenied) {
-			render.Status(r, http.StatusForbidden)
-			render.JSON(w, r, response.Error("role changing not allowed"))
-			return
-		} else if errors.Is(err, service.ErrNotFound) {
+		if errors.Is(err, service.ErrNotFound) {
 			render.Status(r, http.StatusNotFound)
 			render.JSON(w, r, response.Error(err.Error()))
+			return
 		} else {
 			render.Status(r, http.StatusInternalServerError)
 			render.JSON(w, r, response.Error(err.Error()))
@@ -142,15 +211,80 @@ func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, user.ToResponse())
 }
 
+// PUT /user/{id}/role
+func (h *UserHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+
+	var req userType.UpdateRoleRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid request body"))
+		return
+	}
+
+	if err := h.Validator.Struct(req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.ValidationErrorsResp(err.(validator.ValidationErrors)))
+		return
+	}
+
+	user, err := h.Service.UpdateRole(r.Context(), userID, *req.Role)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("user not found"))
+			return
+		}
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
+
+	render.JSON(w, r, user.ToResponse())
+}
+
+// GET /user/search?query
+func (h *UserHandler) Search(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("query"))
+	if err := h.Validator.Var(query, "required,max=64"); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid search query"))
+		return
+	}
+
+	// execute service function
+	params := pagination.FromRequest(r)
+	users, total, err := h.Service.Search(r.Context(), query, params)
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
+
+	// execution result to response
+	res := userType.UserListResponse{
+		Items: make([]*userType.UserResponse, len(users)),
+		Total: total,
+		Page:  params.Page,
+		Limit: params.Limit,
+	}
+	for i, u := range users {
+		res.Items[i] = u.ToResponse()
+	}
+
+	render.JSON(w, r, res)
+}
+
 // DELETE /user
 func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(auth.UserIDKey).(string)
-	err := h.Service.Delete(r.Context(), userID)
+	deletion, err := h.Service.Delete(r.Context(), userID)
 	if err != nil {
 		render.Status(r, http.StatusInternalServerError)
 		render.JSON(w, r, response.Error(err.Error()))
 		return
 	}
 
-	render.Status(r, http.StatusNoContent)
+	// send deletion report
+	render.JSON(w, r, deletion.ToResponse())
 }
@@ -0,0 +1,51 @@
+package userHistory
+
+import (
+	"net/http"
+	"tracker-backend/internal/auth"
+	listenType "tracker-backend/internal/listen/type"
+	"tracker-backend/internal/pkg/pagination"
+	"tracker-backend/internal/pkg/response"
+
+	"github.com/go-chi/render"
+)
+
+type UserHistoryHandler struct {
+	Service *UserHistoryService
+}
+
+func NewUserHistoryHandler(s *UserHistoryService) *UserHistoryHandler {
+	return &UserHistoryHandler{
+		Service: s,
+	}
+}
+
+// GET /user/me/history
+func (h *UserHistoryHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	// get context keys
+	ctx := r.Context()
+	userID := ctx.Value(auth.UserIDKey).(string)
+
+	// execute service function
+	params := pagination.FromRequest(r)
+	items, total, err := h.Service.GetHistory(ctx, userID, params)
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
+
+	// execution result to response
+	res := listenType.HistoryListResponse{
+		Items: make([]listenType.HistoryItemResponse, len(items)),
+		Total: total,
+		Page:  params.Page,
+		Limit: params.Limit,
+	}
+	for i, item := range items {
+		res.Items[i] = item.ToResponse()
+	}
+
+	// send response
+	render.JSON(w, r, res)
+}
@@ -0,0 +1,87 @@
+package userHistory
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	listenType "tracker-backend/internal/listen/type"
+	"tracker-backend/internal/pkg/pagination"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+type UserHistoryService struct {
+	listensCol *mongo.Collection
+}
+
+func NewUserHistoryService(listensCol *mongo.Collection) *UserHistoryService {
+	return &UserHistoryService{
+		listensCol: listensCol,
+	}
+}
+
+type historyResult struct {
+	Items []listenType.HistoryItem `bson:"items"`
+	Total []struct {
+		Count int64 `bson:"count"`
+	} `bson:"total"`
+}
+
+// GetHistory returns listens of user with tracks, the latest first
+func (s *UserHistoryService) GetHistory(
+	ctx context.Context, userID string, params pagination.Params,
+) ([]listenType.HistoryItem, int64, error) {
+	// configure logger
+	logger := slog.With(slog.String("function", "userHistory.UserHistoryService.GetHistory"))
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"userID": userID}},
+		{"$sort": bson.D{
+			{Key: "createdAt", Value: -1},
+			{Key: "id", Value: 1},
+		}},
+		{"$facet": bson.M{
+			"items": bson.A{
+				bson.M{"$skip": params.Skip()},
+				bson.M{"$limit": params.Limit},
+				bson.M{"$lookup": bson.M{
+					"from":         "tracks",
+					"localField":   "trackID",
+					"foreignField": "id",
+					"as":           "track",
+				}},
+				// listens of deleted tracks stay in history
+				bson.M{"$unwind": bson.M{
+					"path":                       "$track",
+					"preserveNullAndEmptyArrays": true,
+				}},
+			},
+			"total": bson.A{
+				bson.M{"$count": "count"},
+			},
+		}},
+	}
+
+	cur, err := s.listensCol.Aggregate(ctx, pipeline)
+	if err != nil {
+		logger.Warn("failed to aggregate", slog.String("error", err.Error()))
+		return nil, 0, errors.New("failed to get history")
+	}
+	defer cur.Close(ctx)
+
+	var result historyResult
+	if cur.Next(ctx) {
+		if err := cur.Decode(&result); err != nil {
+			logger.Warn("failed to decode", slog.String("error", err.Error()))
+			return nil, 0, errors.New("failed to decode history")
+		}
+	}
+
+	var total int64
+	if len(result.Total) > 0 {
+		total = result.Total[0].Count
+	}
+
+	return result.Items, total, nil
+}
@@ -2,23 +2,51 @@ package user
 
 import (
 	"tracker-backend/internal/auth"
+	"tracker-backend/internal/auth/middleware"
+	userHistory "tracker-backend/internal/user/history"
+	userVerification "tracker-backend/internal/user/verification"
 
 	"github.com/go-chi/chi/v5"
 )
 
-func RegisterUserRoutes(r chi.Router, service *UserService, authMiddleware auth.MiddlewareFunc) {
+func RegisterUserRoutes(
+	r chi.Router,
+	service *UserService,
+	userHistorySvc *userHistory.UserHistoryService,
+	userVerificationSvc *userVerification.UserVerificationService,
+	authMiddleware auth.MiddlewareFunc,
+	authRateLimitMiddleware auth.MiddlewareFunc,
+) {
 	h := NewUserHandler(service)
+	hh := userHistory.NewUserHistoryHandler(userHistorySvc)
+	hv := userVerification.NewUserVerificationHandler(userVerificationSvc)
 
 	r.Route("/user", func(r chi.Router) {
-		r.Post("/", h.Register)
-		r.Post("/login", h.Login)
+		// credentials and tokens guessing
+		r.Group(func(r chi.Router) {
+			r.Use(authRateLimitMiddleware)
+
+			r.Post("/", h.Register)
+			r.Post("/login", h.Login)
+			r.Post("/refresh", h.Refresh)
+			r.Post("/verify-email", hv.VerifyEmail)
+			r.Post("/password/forgot", hv.ForgotPassword)
+			r.Post("/password/reset", hv.ResetPassword)
+		})
 
 		r.Group(func(r chi.Router) {
 			r.Use(authMiddleware)
 
 			r.Get("/me", h.Me)
+			r.Get("/me/history", hh.GetHistory)
 			r.Put("/", h.Update)
 			r.Delete("/", h.Delete)
+			r.Post("/logout", h.Logout)
+			r.Post("/logout/all", h.LogoutAll)
+			r.With(authRateLimitMiddleware).Post("/verify-email/send", hv.ResendVerification)
+
+			r.With(middleware.RequireRole(auth.RoleModerator)).Get("/search", h.Search)
+			r.With(middleware.RequireRole(auth.RoleAdmin)).Put("/{id}/role", h.UpdateRole)
 		})
 	})
 }
@@ -5,12 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"os"
+	"regexp"
 	"strings"
 	"time"
 	authService "tracker-backend/internal/auth"
-	"tracker-backend/internal/config"
+	"tracker-backend/internal/auth/lockout"
+	"tracker-backend/internal/auth/session"
+	"tracker-backend/internal/cascade"
 	auth "tracker-backend/internal/pkg/authorization"
+	"tracker-backend/internal/pkg/pagination"
 	"tracker-backend/internal/pkg/service"
 	playlistType "tracker-backend/internal/playlist/type"
 	userType "tracker-backend/internal/user/type"
@@ -18,6 +21,7 @@ import (
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -25,19 +29,56 @@ type PlaylistCreator interface {
 	Create(context.Context, playlistType.PlaylistCreateRequest) (*playlistType.Playlist, error)
 }
 
+// UserDeleter removes user with artists, playlists and listens
+type UserDeleter interface {
+	DeleteUser(ctx context.Context, userID string) (*cascade.Deletion, error)
+}
+
+// SessionManager issues and revokes user sessions
+type SessionManager interface {
+	Issue(ctx context.Context, user *authService.AuthUser) (*session.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*session.TokenPair, error)
+	Revoke(ctx context.Context, userID, sessionID string) error
+	RevokeUser(ctx context.Context, userID string) error
+}
+
+// LoginLimiter locks login after repeated failed attempts from client ip
+type LoginLimiter interface {
+	Check(ctx context.Context, login, ip string) error
+	Fail(ctx context.Context, login, ip string) error
+	Reset(ctx context.Context, login, ip string) error
+}
+
+// VerificationSender sends email verification link
+type VerificationSender interface {
+	SendVerification(ctx context.Context, user *userType.User) error
+}
+
 type UserService struct {
 	Col *mongo.Collection
 	pc  PlaylistCreator
+	ud  UserDeleter
+	sm  SessionManager
+	vs  VerificationSender
+	ll  LoginLimiter
 }
 
 func NewUserService(
 	ctx context.Context,
 	usersCol *mongo.Collection,
 	pc PlaylistCreator,
+	ud UserDeleter,
+	sm SessionManager,
+	vs VerificationSender,
+	ll LoginLimiter,
 ) *UserService {
 	return &UserService{
 		Col: usersCol,
 		pc:  pc,
+		ud:  ud,
+		sm:  sm,
+		vs:  vs,
+		ll:  ll,
 	}
 }
 
@@ -102,39 +143,105 @@ func (s *UserService) Register(
 	// update default playlist pointer
 	_, err = s.Col.UpdateOne(ctx,
 		bson.M{"id": user.ID},
-		bson.M{"$set": bson.M{"myChoisePlaylist": p.ID}},
+		bson.M{"$set": bson.M{"myChoicePlaylist": p.ID}},
 	)
 	if err != nil {
 		return user, errors.New("failed to update default playlist")
 	}
+	user.MyChoicePlaylist = p.ID
+
+	// user is registered anyway, verification link can be requested again
+	if err := s.vs.SendVerification(ctx, user); err != nil {
+		slog.Warn("failed to send verification email",
+			slog.String("function", "user.UserService.Register"),
+			slog.String("userID", user.ID),
+			slog.String("error", err.Error()),
+		)
+	}
 
 	return user, nil
 }
 
 func (s *UserService) Login(
-	ctx context.Context, credentials userType.LoginRequest,
-) (string, error) {
+	ctx context.Context, credentials userType.LoginRequest, clientIP string,
+) (*session.TokenPair, error) {
 	// configure logger
-	logger := slog.With(slog.String("function", "track.TrackService.Create"))
+	logger := slog.With(slog.String("function", "user.UserService.Login"))
+
+	// locked login is rejected before password check,
+	// lockout failures don't block login
+	if err := s.ll.Check(ctx, credentials.Login, clientIP); err != nil {
+		if errors.Is(err, lockout.ErrLocked) {
+			return nil, err
+		}
+		logger.Warn("failed to check login lockout", slog.String("error", err.Error()))
+	}
 
 	var user userType.User
 
 	err := s.Col.FindOne(ctx, bson.M{"login": credentials.Login}).Decode(&user)
 
 	if err != nil {
-		return "", service.ErrNotFound
+		return nil, s.loginFailed(ctx, credentials.Login, clientIP, service.ErrNotFound)
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash),
 		[]byte(credentials.Password)); err != nil {
-		return "", service.ErrAccessDenied
+		return nil, s.loginFailed(ctx, credentials.Login, clientIP, service.ErrAccessDenied)
+	}
+
+	if err := s.ll.Reset(ctx, credentials.Login, clientIP); err != nil {
+		logger.Warn("failed to reset login failures", slog.String("error", err.Error()))
 	}
 
 	logger.Info("user authorized", slog.String("id", user.ID))
 
-	return auth.CreateAuthToken(
-		user.Role, user.ID, user.Email, os.Getenv(config.JWTSecretEnvName),
-	)
+	return s.sm.Issue(ctx, &authService.AuthUser{
+		ID:    user.ID,
+		Email: user.Email,
+		Role:  user.Role,
+	})
+}
+
+// loginFailed counts failed attempt, returns lockout error
+// if the attempt locked login or err otherwise
+func (s *UserService) loginFailed(
+	ctx context.Context, login, clientIP string, err error,
+) error {
+	if lockErr := s.ll.Fail(ctx, login, clientIP); lockErr != nil {
+		if errors.Is(lockErr, lockout.ErrLocked) {
+			return lockErr
+		}
+		slog.Warn("failed to count login failure",
+			slog.String("function", "user.UserService.loginFailed"),
+			slog.String("error", lockErr.Error()),
+		)
+	}
+	return err
+}
+
+// Refresh exchanges refresh token for new token pair
+func (s *UserService) Refresh(
+	ctx context.Context, refreshToken string,
+) (*session.TokenPair, error) {
+	return s.sm.Refresh(ctx, refreshToken)
+}
+
+// Logout revokes current session of user
+func (s *UserService) Logout(
+	ctx context.Context, userID, sessionID string,
+) error {
+	if err := s.sm.Revoke(ctx, userID, sessionID); err != nil {
+		return errors.New("failed to revoke session")
+	}
+	return nil
+}
+
+// LogoutAll revokes all sessions of user
+func (s *UserService) LogoutAll(
+	ctx context.Context, userID string,
+) error {
+	return s.sm.RevokeUser(ctx, userID)
 }
 
 func (s *UserService) GetByID(
@@ -164,15 +271,25 @@ func (s *UserService) GetAuthDTOByID(
 }
 
 func (s *UserService) Update(
-	ctx context.Context, id string, req userType.UpdateRequest, allowed bool,
+	ctx context.Context, id string, req userType.UpdateRequest,
 ) (*userType.User, error) {
 	update := bson.M{}
+	emailChanged := false
 
 	if req.Login != nil {
 		update["login"] = *req.Login
 	}
 	if req.Email != nil {
+		current, err := s.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
 		update["email"] = *req.Email
+		// new email must be verified again
+		if current.Email != *req.Email {
+			update["emailVerified"] = false
+			emailChanged = true
+		}
 	}
 	if req.Password != nil {
 		hashed, err := auth.HashPassword(*req.Password)
@@ -181,13 +298,6 @@ func (s *UserService) Update(
 		}
 		update["passwordHash"] = hashed
 	}
-	if req.Role != nil {
-		if !allowed {
-			return nil, service.ErrAccessDenied
-		} else {
-			update["role"] = *req.Role
-		}
-	}
 
 	filter := bson.M{"id": id}
 
@@ -221,18 +331,103 @@ func (s *UserService) Update(
 		return nil, fmt.Errorf("updating user: %w", err)
 	}
 
+	// tokens issued with old password are not valid anymore
+	if req.Password != nil {
+		if err := s.sm.RevokeUser(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
 	// get updated user
 	user, _ := s.GetByID(ctx, id)
 
+	if emailChanged && user != nil {
+		if err := s.vs.SendVerification(ctx, user); err != nil {
+			slog.Warn("failed to send verification email",
+				slog.String("function", "user.UserService.Update"),
+				slog.String("userID", id),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+
 	return user, nil
 }
 
+// UpdateRole sets role of user,
+// route is available to admins only
+func (s *UserService) UpdateRole(
+	ctx context.Context, id string, role int,
+) (*userType.User, error) {
+	res, err := s.Col.UpdateOne(ctx, bson.M{"id": id}, bson.M{"$set": bson.M{"role": role}})
+	if err != nil {
+		return nil, fmt.Errorf("updating user role: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return nil, service.ErrNotFound
+	}
+
+	// tokens issued with old role are not valid anymore
+	if err := s.sm.RevokeUser(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(ctx, id)
+}
+
+// Search finds users by login or email substring
+func (s *UserService) Search(
+	ctx context.Context, query string, params pagination.Params,
+) ([]userType.User, int64, error) {
+	// escape regex special characters of user input
+	pattern := bson.Regex{Pattern: regexp.QuoteMeta(query), Options: "i"}
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"login": pattern},
+			bson.M{"email": pattern},
+		},
+	}
+
+	total, err := s.Col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, errors.New("failed to count users")
+	}
+
+	cur, err := s.Col.Find(ctx, filter,
+		options.Find().
+			SetSort(bson.D{{Key: "login", Value: 1}}).
+			SetSkip(params.Skip()).
+			SetLimit(params.Limit),
+	)
+	if err != nil {
+		return nil, 0, errors.New("failed to find users")
+	}
+	defer cur.Close(ctx)
+
+	users := []userType.User{}
+	if err := cur.All(ctx, &users); err != nil {
+		return nil, 0, errors.New("failed to decode cursor")
+	}
+
+	return users, total, nil
+}
+
 func (s *UserService) Delete(
 	ctx context.Context, id string,
-) error {
-	res, err := s.Col.DeleteOne(ctx, bson.M{"id": id})
-	if res.DeletedCount < 1 {
-		return service.ErrNotFound
+) (*cascade.Deletion, error) {
+	deletion, err := s.ud.DeleteUser(ctx, id)
+	if err != nil {
+		return nil, err
 	}
-	return err
+
+	// deleted user tokens are rejected anyway, sessions are cleaned up
+	if err := s.sm.RevokeUser(ctx, id); err != nil {
+		slog.Warn("failed to revoke sessions of deleted user",
+			slog.String("function", "user.UserService.Delete"),
+			slog.String("userID", id),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	return deletion, nil
 }
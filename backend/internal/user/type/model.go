@@ -11,13 +11,41 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+// TokenResponse is returned on login and refresh,
+// expiresIn is access token lifetime in seconds
+type TokenResponse struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int64  `json:"expiresIn"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=6,max=64"`
+}
+
 type UpdateRequest struct {
 	Login *string `json:"login,omitempty" validate:"omitempty,min=3,max=32"`
 	Email *string `json:"email,omitempty" validate:"omitempty,email"`
 	// add possability to update password
 	Password *string `json:"password,omitempty" validate:"omitempty,min=6,max=64"`
-	// add possability to update role if 'Allow-Access header is set'
-	Role *int `json:"role" validate:"omitempty,role"`
+}
+
+// UpdateRoleRequest changes role of user, admins only
+type UpdateRoleRequest struct {
+	Role *int `json:"role" validate:"required,oneof=1 2 3"`
 }
 
 type UserResponse struct {
@@ -27,6 +55,14 @@ type UserResponse struct {
 	PasswordHash     string `json:"passwordHash"`
 	MyChoicePlaylist string `json:"myChoicePlaylist"`
 	Role             int    `json:"role"`
+	EmailVerified    bool   `json:"emailVerified"`
+}
+
+type UserListResponse struct {
+	Items []*UserResponse `json:"items"`
+	Total int64           `json:"total"`
+	Page  int64           `json:"page"`
+	Limit int64           `json:"limit"`
 }
 
 func (u *User) ToResponse() *UserResponse {
@@ -37,5 +73,6 @@ func (u *User) ToResponse() *UserResponse {
 		PasswordHash:     u.PasswordHash,
 		MyChoicePlaylist: u.MyChoicePlaylist,
 		Role:             u.Role,
+		EmailVerified:    u.EmailVerified,
 	}
 }
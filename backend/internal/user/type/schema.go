@@ -17,6 +17,8 @@ type User struct {
 	MyChoicePlaylist string    `bson:"myChoicePlaylist" json:"myChoicePlaylist"`
 	CreatedAt        time.Time `bson:"createdAt" json:"createdAt"`
 	Role             int       `bson:"role" json:"role"`
+	// users registered before verification have unverified email
+	EmailVerified bool `bson:"emailVerified" json:"emailVerified"`
 }
 
 func EnsureIndexes(ctx context.Context, col *mongo.Collection) error {
@@ -39,3 +41,16 @@ func EnsureIndexes(ctx context.Context, col *mongo.Collection) error {
 	_, err := col.Indexes().CreateMany(ctx, []mongo.IndexModel{loginIndex, emailIndex, idIndex})
 	return err
 }
+
+// MigrateDefaultPlaylistField moves default playlist
+// saved to misspelled field "myChoisePlaylist" to "myChoicePlaylist"
+func MigrateDefaultPlaylistField(ctx context.Context, col *mongo.Collection) error {
+	_, err := col.UpdateMany(ctx,
+		bson.M{"myChoisePlaylist": bson.M{"$exists": true}},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{"myChoicePlaylist": "$myChoisePlaylist"}}},
+			{{Key: "$unset", Value: "myChoisePlaylist"}},
+		},
+	)
+	return err
+}
@@ -0,0 +1,131 @@
+package userVerification
+
+import (
+	"errors"
+	"net/http"
+	"tracker-backend/internal/auth"
+	"tracker-backend/internal/auth/onetime"
+	"tracker-backend/internal/pkg/response"
+	"tracker-backend/internal/pkg/service"
+	userType "tracker-backend/internal/user/type"
+
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+)
+
+type UserVerificationHandler struct {
+	Service   *UserVerificationService
+	Validator *validator.Validate
+}
+
+func NewUserVerificationHandler(s *UserVerificationService) *UserVerificationHandler {
+	return &UserVerificationHandler{
+		Service:   s,
+		Validator: validator.New(),
+	}
+}
+
+// POST /user/verify-email
+func (h *UserVerificationHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req userType.VerifyEmailRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid request body"))
+		return
+	}
+
+	if err := h.Validator.Struct(req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.ValidationErrorsResp(err.(validator.ValidationErrors)))
+		return
+	}
+
+	if err := h.Service.VerifyEmail(r.Context(), req.Token); err != nil {
+		renderTokenError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /user/verify-email/send
+func (h *UserVerificationHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(auth.UserIDKey).(string)
+
+	err := h.Service.ResendVerification(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrAlreadyVerified):
+			render.Status(r, http.StatusConflict)
+		case errors.Is(err, service.ErrNotFound):
+			render.Status(r, http.StatusNotFound)
+		default:
+			render.Status(r, http.StatusInternalServerError)
+		}
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// POST /user/password/forgot
+func (h *UserVerificationHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req userType.ForgotPasswordRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid request body"))
+		return
+	}
+
+	if err := h.Validator.Struct(req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.ValidationErrorsResp(err.(validator.ValidationErrors)))
+		return
+	}
+
+	if err := h.Service.ForgotPassword(r.Context(), req.Email); err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
+
+	// accepted whether user exists or not
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// POST /user/password/reset
+func (h *UserVerificationHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req userType.ResetPasswordRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid request body"))
+		return
+	}
+
+	if err := h.Validator.Struct(req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.ValidationErrorsResp(err.(validator.ValidationErrors)))
+		return
+	}
+
+	if err := h.Service.ResetPassword(r.Context(), req.Token, req.Password); err != nil {
+		renderTokenError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// renderTokenError renders error of one-time token consuming
+func renderTokenError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, onetime.ErrInvalidToken):
+		render.Status(r, http.StatusBadRequest)
+	case errors.Is(err, service.ErrNotFound):
+		render.Status(r, http.StatusNotFound)
+	default:
+		render.Status(r, http.StatusInternalServerError)
+	}
+	render.JSON(w, r, response.Error(err.Error()))
+}
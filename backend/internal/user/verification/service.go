@@ -0,0 +1,255 @@
+package userVerification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+	"tracker-backend/internal/auth"
+	"tracker-backend/internal/auth/onetime"
+	authToken "tracker-backend/internal/pkg/authorization"
+	"tracker-backend/internal/pkg/mailer"
+	"tracker-backend/internal/pkg/service"
+	userType "tracker-backend/internal/user/type"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var ErrAlreadyVerified = errors.New("email already verified")
+
+const (
+	verifyEmailTTL   = 24 * time.Hour
+	resetPasswordTTL = time.Hour
+)
+
+// SessionRevoker revokes user sessions after password reset
+type SessionRevoker interface {
+	RevokeUser(ctx context.Context, userID string) error
+}
+
+// UserVerificationService verifies user emails and resets forgotten passwords
+// by one-time tokens sent to user email
+type UserVerificationService struct {
+	usersCol *mongo.Collection
+	tokens   *onetime.TokenService
+	mailer   mailer.Mailer
+	sessions SessionRevoker
+	// frontend url, email links lead to its pages
+	appURL string
+	// uploads of users with unverified email are blocked
+	requireVerified bool
+}
+
+func NewUserVerificationService(
+	usersCol *mongo.Collection,
+	tokens *onetime.TokenService,
+	mailer mailer.Mailer,
+	sessions SessionRevoker,
+	appURL string,
+	requireVerified bool,
+) *UserVerificationService {
+	return &UserVerificationService{
+		usersCol:        usersCol,
+		tokens:          tokens,
+		mailer:          mailer,
+		sessions:        sessions,
+		appURL:          strings.TrimSuffix(appURL, "/"),
+		requireVerified: requireVerified,
+	}
+}
+
+// SendVerification sends email verification link to user
+func (s *UserVerificationService) SendVerification(
+	ctx context.Context, user *userType.User,
+) error {
+	// configure logger
+	logger := slog.With(slog.String("function", "userVerification.UserVerificationService.SendVerification"))
+
+	if user.EmailVerified {
+		return ErrAlreadyVerified
+	}
+
+	token, err := s.tokens.Issue(ctx, onetime.PurposeVerifyEmail, user.ID, verifyEmailTTL)
+	if err != nil {
+		logger.Warn("failed to issue token", slog.String("error", err.Error()))
+		return errors.New("failed to issue token")
+	}
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf(
+			"Hi, %s!\n\nConfirm your email by following the link:\n%s\n\n"+
+				"The link is valid for 24 hours. If you didn't register, ignore this email.\n",
+			user.Login, s.link("/verify-email", token),
+		),
+	})
+	if err != nil {
+		logger.Warn("failed to send email",
+			slog.String("userID", user.ID),
+			slog.String("error", err.Error()),
+		)
+		return errors.New("failed to send email")
+	}
+
+	return nil
+}
+
+// ResendVerification sends verification link to user again
+func (s *UserVerificationService) ResendVerification(
+	ctx context.Context, userID string,
+) error {
+	var user userType.User
+	if err := s.usersCol.FindOne(ctx, bson.M{"id": userID}).Decode(&user); err != nil {
+		return service.ErrNotFound
+	}
+
+	return s.SendVerification(ctx, &user)
+}
+
+// VerifyEmail marks email of token user as verified
+func (s *UserVerificationService) VerifyEmail(
+	ctx context.Context, token string,
+) error {
+	userID, err := s.tokens.Consume(ctx, onetime.PurposeVerifyEmail, token)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.usersCol.UpdateOne(ctx,
+		bson.M{"id": userID},
+		bson.M{"$set": bson.M{"emailVerified": true}},
+	)
+	if err != nil {
+		return errors.New("failed to verify email")
+	}
+	if res.MatchedCount < 1 {
+		return service.ErrNotFound
+	}
+
+	slog.Info("email verified",
+		slog.String("function", "userVerification.UserVerificationService.VerifyEmail"),
+		slog.String("userID", userID),
+	)
+	return nil
+}
+
+// ForgotPassword sends password reset link if user with email exists,
+// missing user isn't reported so emails can't be enumerated
+func (s *UserVerificationService) ForgotPassword(
+	ctx context.Context, email string,
+) error {
+	// configure logger
+	logger := slog.With(slog.String("function", "userVerification.UserVerificationService.ForgotPassword"))
+
+	var user userType.User
+	err := s.usersCol.FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			logger.Info("password reset of unknown email requested")
+			return nil
+		}
+		return errors.New("failed to find user")
+	}
+
+	token, err := s.tokens.Issue(ctx, onetime.PurposeResetPassword, user.ID, resetPasswordTTL)
+	if err != nil {
+		logger.Warn("failed to issue token", slog.String("error", err.Error()))
+		return errors.New("failed to issue token")
+	}
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi, %s!\n\nSet a new password by following the link:\n%s\n\n"+
+				"The link is valid for 1 hour. If you didn't request password reset, ignore this email.\n",
+			user.Login, s.link("/reset-password", token),
+		),
+	})
+	if err != nil {
+		logger.Warn("failed to send email",
+			slog.String("userID", user.ID),
+			slog.String("error", err.Error()),
+		)
+		return errors.New("failed to send email")
+	}
+
+	return nil
+}
+
+// ResetPassword sets new password of token user and revokes all user sessions,
+// email is verified as the token was received by it
+func (s *UserVerificationService) ResetPassword(
+	ctx context.Context, token, password string,
+) error {
+	// configure logger
+	logger := slog.With(slog.String("function", "userVerification.UserVerificationService.ResetPassword"))
+
+	userID, err := s.tokens.Consume(ctx, onetime.PurposeResetPassword, token)
+	if err != nil {
+		return err
+	}
+
+	hash, err := authToken.HashPassword(password)
+	if err != nil {
+		return errors.New("error while hashing password")
+	}
+
+	res, err := s.usersCol.UpdateOne(ctx,
+		bson.M{"id": userID},
+		bson.M{"$set": bson.M{
+			"passwordHash":  hash,
+			"emailVerified": true,
+		}},
+	)
+	if err != nil {
+		return errors.New("failed to reset password")
+	}
+	if res.MatchedCount < 1 {
+		return service.ErrNotFound
+	}
+
+	if err := s.sessions.RevokeUser(ctx, userID); err != nil {
+		return err
+	}
+
+	logger.Info("password reset", slog.String("userID", userID))
+	return nil
+}
+
+// CheckEmailVerified returns auth.ErrEmailNotVerified
+// if verification is required and user email is not verified
+func (s *UserVerificationService) CheckEmailVerified(
+	ctx context.Context, userID string,
+) error {
+	if !s.requireVerified {
+		return nil
+	}
+
+	var user struct {
+		EmailVerified bool `bson:"emailVerified"`
+	}
+	err := s.usersCol.FindOne(ctx,
+		bson.M{"id": userID},
+		options.FindOne().SetProjection(bson.M{"emailVerified": 1}),
+	).Decode(&user)
+	if err != nil {
+		return service.ErrNotFound
+	}
+
+	if !user.EmailVerified {
+		return auth.ErrEmailNotVerified
+	}
+	return nil
+}
+
+// link returns frontend page link with token
+func (s *UserVerificationService) link(page, token string) string {
+	return s.appURL + page + "?" + url.Values{"token": {token}}.Encode()
+}